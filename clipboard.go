@@ -0,0 +1,32 @@
+package jid
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+)
+
+// osc52 returns the escape sequence that asks the terminal to put s on the
+// system clipboard. It travels with the rest of the output, so it also works
+// over SSH. tmux only hands it on to the outer terminal when it is wrapped in
+// a passthrough sequence with every ESC doubled.
+func osc52(s string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(s)) + "\a"
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// copyToClipboard writes the OSC 52 sequence for s to the controlling
+// terminal. The terminal is opened directly because stdout is usually
+// redirected to receive the result.
+func copyToClipboard(s string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(osc52(s, os.Getenv("TMUX") != ""))
+	return err
+}
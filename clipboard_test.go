@@ -0,0 +1,14 @@
+package jid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSC52(t *testing.T) {
+	var assert = assert.New(t)
+
+	assert.Equal("\x1b]52;c;eyJhIjoxfQ==\a", osc52(`{"a":1}`, false))
+	assert.Equal("\x1bPtmux;\x1b\x1b]52;c;eyJhIjoxfQ==\a\x1b\\", osc52(`{"a":1}`, true))
+}
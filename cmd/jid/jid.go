@@ -1,31 +1,89 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
 
 	"github.com/simeji/jid"
 )
 
 const VERSION = "1.1.2"
 
+// fileList collects the values of a repeatable flag.
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
 func main() {
 	content := os.Stdin
 
 	var qm bool
+	var out outputOption
 	var help bool
 	var version bool
 	var mono bool
 	var pretty bool
+	var lines bool
+	var slurp bool
+	var inputFormat string
+	var delimiter string
+	var noHeader bool
+	var lenient bool
+	var files fileList
+	var xmlArrays string
+	var lazy bool
+	var watch bool
+	var command string
+	var output string
+	var columns string
+	var querySyntax string
+	var templateText string
+	var templateFile string
+	var canonical bool
 	qs := "."
 
 	flag.BoolVar(&qm, "q", false, "Output query mode")
+	flag.StringVar(&querySyntax, "query-syntax", "", "print the query (like -q) translated to jq, jsonpath, jmespath or pointer")
+	flag.BoolVar(&out.raw, "r", false, "print a string result without quotes and an array of scalars one per line")
+	flag.BoolVar(&out.join, "j", false, "like -r, without a trailing newline")
+	flag.BoolVar(&out.nul, "0", false, "like -r, terminating each value with NUL (for xargs -0)")
 	flag.BoolVar(&help, "h", false, "print a help")
 	flag.BoolVar(&help, "help", false, "print a help")
 	flag.BoolVar(&version, "version", false, "print the version and exit")
 	flag.BoolVar(&mono, "M", false, "monochrome output mode")
 	flag.BoolVar(&pretty, "p", false, "pretty print json result")
+	flag.BoolVar(&lines, "lines", false, "read JSON Lines (one JSON value per line) as an array")
+	flag.BoolVar(&slurp, "s", false, "read a stream of concatenated JSON values as an array")
+	flag.BoolVar(&slurp, "slurp", false, "read a stream of concatenated JSON values as an array")
+	flag.StringVar(&inputFormat, "input-format", "", "input format: json, lines, slurp, yaml, toml, csv, tsv, msgpack, cbor, xml, gron (default: auto-detect)")
+	flag.StringVar(&xmlArrays, "xml-array", "", "comma separated XML element names that are always arrays")
+	flag.StringVar(&delimiter, "delimiter", "", "field delimiter for csv/tsv input (a single character or \\t)")
+	flag.BoolVar(&noHeader, "no-header", false, "csv/tsv input has no header row; columns are keyed by index")
+	flag.Var(&files, "f", "load JSON from a file (repeatable)")
+	flag.Var(&files, "file", "load JSON from a file (repeatable)")
+	flag.BoolVar(&lenient, "lenient", false, "accept JSONC / JSON5 input (comments, trailing commas, single quotes, unquoted keys)")
+	flag.BoolVar(&lazy, "lazy", false, "index a large JSON file and parse subtrees only when the query reaches them")
+	flag.BoolVar(&watch, "watch", false, "reload the files given with -f when they change and re-run the query")
+	flag.StringVar(&command, "cmd", "", "run a shell command and load its stdout (CTRL-R runs it again)")
+	flag.StringVar(&output, "output", "", "output format of the result: json, yaml, csv, tsv, gron, go, ts, schema (default: json)")
+	flag.StringVar(&columns, "columns", "", "comma separated keys picked and ordered as the columns of csv/tsv output")
+	flag.StringVar(&templateText, "template", "", "print the result with a Go text/template instead of JSON")
+	flag.StringVar(&templateFile, "template-file", "", "like --template, reading the template from a file")
+	flag.BoolVar(&canonical, "canonical", false, "print the result as RFC 8785 canonical JSON (compact, sorted keys)")
 	flag.Parse()
 
 	if help {
@@ -43,10 +101,76 @@ func main() {
 		qs = args[0]
 	}
 
+	format, err := jid.ParseInputFormat(inputFormat)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if lines {
+		format = jid.InputFormatLines
+	}
+	if slurp {
+		format = jid.InputFormatSlurp
+	}
+	if querySyntax != "" {
+		if out.syntax, err = jid.ParseQuerySyntax(querySyntax); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		qm = true
+	}
+	outputFormat, err := jid.ParseOutputFormat(output)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	tmpl, err := parseTemplate(templateText, templateFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if tmpl != nil {
+		if output != "" && outputFormat != jid.OutputJSON {
+			fmt.Println("--template cannot be combined with --output")
+			os.Exit(1)
+		}
+		outputFormat = jid.OutputTemplate
+	}
+	if canonical {
+		if outputFormat != jid.OutputJSON {
+			fmt.Println("--canonical cannot be combined with --output or --template")
+			os.Exit(1)
+		}
+		outputFormat = jid.OutputCanonical
+	}
+	delim, err := parseDelimiter(delimiter)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	ea := &jid.EngineAttribute{
 		DefaultQuery: qs,
 		Monochrome:   mono,
 		PrettyResult: pretty,
+		Input: jid.InputOption{
+			Format:    format,
+			Delimiter: delim,
+			NoHeader:  noHeader,
+			Lenient:   lenient,
+			XMLArrays: splitList(xmlArrays),
+			Lazy:      lazy,
+		},
+		Files:   files,
+		Watch:   watch,
+		Command: command,
+		Output:  jid.OutputOption{Format: outputFormat, Columns: splitList(columns), Template: tmpl},
+	}
+
+	if lazy {
+		if st, err := os.Stderr.Stat(); err == nil && st.Mode()&os.ModeCharDevice != 0 {
+			ea.Input.Progress = progressPrinter(os.Stderr)
+		}
 	}
 
 	e, err := jid.NewEngine(content, ea)
@@ -55,29 +179,380 @@ func main() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	os.Exit(run(e, qm))
+	out.queryMode = qm
+	os.Exit(run(e, out))
+}
+
+// progressPrinter returns a callback that shows the indexing progress of a
+// lazy load as a percentage and clears it when done.
+func progressPrinter(w io.Writer) func(done, total int64) {
+	last := int64(-1)
+	return func(done, total int64) {
+		if total <= 0 {
+			return
+		}
+		if done >= total {
+			fmt.Fprint(w, "\r\033[K")
+			return
+		}
+		if pct := done * 100 / total; pct != last {
+			last = pct
+			fmt.Fprintf(w, "\rindexing %d%%", pct)
+		}
+	}
+}
+
+// outputOption controls how run prints the result.
+type outputOption struct {
+	queryMode bool // -q: print the query instead of the result
+	raw       bool // -r
+	join      bool // -j
+	nul       bool // -0
+	// syntax translates the printed query (--query-syntax)
+	syntax jid.QuerySyntax
 }
 
-func run(e jid.EngineInterface, qm bool) int {
+var stdout io.Writer = os.Stdout
+
+func run(e jid.EngineInterface, o outputOption) int {
 
 	result := e.Run()
-	if result.GetError() != nil {
+	if err := result.GetError(); err != nil {
+		if msg := err.Error(); msg != "" {
+			fmt.Fprintln(os.Stderr, msg)
+		}
 		return 2
 	}
-	if qm {
-		fmt.Printf("%s", result.GetQueryString())
+	if o.queryMode {
+		qs := result.GetQueryString()
+		if o.syntax != "" && qs != "" {
+			var err error
+			if qs, err = jid.TranslateQuery(qs, o.syntax); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+		fmt.Fprintf(stdout, "%s", qs)
 	} else {
-		fmt.Printf("%s", result.GetContent())
+		fmt.Fprintf(stdout, "%s", formatOutput(result.GetContent(), o))
 	}
 	return 0
 }
 
+// formatOutput renders the JSON result for printing. An empty result, as left
+// by CTRL-C, prints nothing. Without -r, -j or -0 it is printed as is. Otherwise a string is printed without quotes and escapes
+// and an array of scalars one value per line (NUL terminated with -0);
+// anything else stays JSON.
+func formatOutput(content string, o outputOption) string {
+	if content == "" || !o.raw && !o.join && !o.nul {
+		return content
+	}
+	items, ok := rawItems(content)
+	if !ok {
+		items = []string{content}
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	sep := "\n"
+	if o.nul {
+		sep = "\x00"
+	}
+	s := strings.Join(items, sep)
+	if !o.join {
+		s += sep
+	}
+	return s
+}
+
+// rawItems returns the values of a string, scalar or array of scalars as they
+// are printed in raw mode.
+func rawItems(content string) ([]string, bool) {
+	d := json.NewDecoder(strings.NewReader(content))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, false
+	}
+	if a, ok := v.([]interface{}); ok {
+		items := make([]string, 0, len(a))
+		for _, e := range a {
+			s, ok := rawScalar(e)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, s)
+		}
+		return items, true
+	}
+	s, ok := rawScalar(v)
+	if !ok {
+		return nil, false
+	}
+	return []string{s}, true
+}
+
+func rawScalar(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	case bool:
+		return strconv.FormatBool(t), true
+	case nil:
+		return "null", true
+	}
+	return "", false
+}
+
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDelimiter converts the --delimiter value to a rune. "" means the
+// default of the input format; "\t" and "tab" select a tab.
+func parseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' {
+		return 0, fmt.Errorf("invalid delimiter: %q", s)
+	}
+	return r[0], nil
+}
+
+// parseTemplate parses the --template text or the --template-file file. It
+// returns nil when neither is given.
+func parseTemplate(text string, path string) (*template.Template, error) {
+	switch {
+	case text != "" && path != "":
+		return nil, fmt.Errorf("--template and --template-file cannot be used together")
+	case path != "":
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return jid.ParseTemplate(filepath.Base(path), string(b))
+	case text != "":
+		return jid.ParseTemplate("template", text)
+	}
+	return nil, nil
+}
+
 func getHelpString() string {
 	return `
 
 ============ Load JSON from a file ==============
 
 $ jid < file.json
+$ jid < file.json.gz
+
+gzip, bzip2 and zlib compressed input is decompressed automatically.
+
+============ Load and compare several files =====
+
+$ jid -f staging.json -f prod.json '.services'
+
+The active file name is shown at the right of the filter line.
+CTRL-O switches to the next file and keeps the current query.
+
+============ Load JSON Lines (NDJSON) ===========
+
+$ jid --lines < app.log
+
+Each line is parsed as its own record and the records are shown as one array.
+Multi-line input that is not a single JSON document is detected automatically.
+
+============ Load concatenated JSON values ======
+
+$ kubectl get pods -o json | jid --slurp
+
+Every top-level value ({...}{...} or whitespace separated) becomes one
+element of the root array. The number of documents read is shown at the
+right of the filter line.
+
+============ Load YAML ==========================
+
+$ jid --input-format yaml < deployment.yaml
+
+Multi-document streams (separated by ---) become a root array.
+YAML is also detected automatically when the input is not JSON.
+
+============ Load TOML ==========================
+
+$ jid --input-format toml < Cargo.toml
+
+Datetimes are shown as RFC 3339 strings.
+
+============ Load CSV / TSV =====================
+
+$ jid --input-format csv < export.csv
+$ jid --input-format csv --delimiter ';' --no-header < data.csv
+
+Rows become objects keyed by the header row (or by column index with
+--no-header). Numbers, true/false and empty cells (null) are typed.
+
+============ Load MessagePack / CBOR ============
+
+$ jid --input-format msgpack < cache.msgpack
+$ jid < payload.cbor
+
+A top-level map or array is detected automatically from the first byte.
+Binary values are shown as strings with a "base64:" prefix.
+
+============ Load XML ===========================
+
+$ jid --input-format xml < pom.xml
+$ jid --input-format xml --xml-array dependency < pom.xml
+
+Attributes become "@name", text becomes "#text" and repeated elements
+become arrays. --xml-array forces the named elements to always be arrays.
+Input starting with '<' is detected automatically.
+
+============ Flatten to gron lines ===============
+
+$ jid --output gron < doc.json | grep email
+json.users[0].email = "ann@example.com";
+$ jid --output gron < a.json > a.gron; jid --output gron < b.json | diff a.gron -
+$ grep email a.gron | jid --input-format gron
+
+--output gron prints every value of the result as one assignment per
+line, with the path written as in the query line. Lines like these, also
+after grep, are read back with --input-format gron (or detected
+automatically) and rebuilt into JSON.
+
+============ Load JSONC / JSON5 =================
+
+$ jid --lenient < tsconfig.json
+
+Comments, trailing commas, single-quoted strings, unquoted keys and
+NaN / Infinity (shown as null) are accepted. The lenient parser is also
+tried automatically when strict parsing fails; [lenient] is then shown at
+the right of the filter line.
+
+============ Load the output of a command =======
+
+$ jid --cmd 'kubectl get pods -o json' '.items[*].status.phase'
+
+The command is run with sh -c (cmd /C on Windows) and its stdout is
+loaded. CTRL-R runs it again and refreshes the view without losing the
+query. The time of the last run and its exit status are shown at the
+right of the filter line.
+
+============ Watch files =======================
+
+$ jid --watch -f status.json
+$ jid --watch --lines -f app.log.json
+
+The file is reloaded when it changes on disk; the query and the scroll
+position are kept. For JSON Lines input the view follows newly appended
+records while it is scrolled to the bottom, like tail -f. If the file
+cannot be parsed (e.g. half written) the previous content stays and
+[reload failed] is shown.
+
+============ Load a very large file =============
+
+$ jid --lazy -f dump.json
+$ jid --lazy < dump.json
+
+The file is indexed instead of read into memory and only the parts the
+query reaches are parsed. Big values are shown as "(lazy array, 1.2 GB)"
+until the query descends into them, and long arrays list their first
+elements only. JMESPath expressions parse the whole value they start from,
+so begin them below the root, e.g. .users[*].name. Needs an uncompressed
+JSON file; [lazy] is shown at the right of the filter line.
+
+============ Print raw values ===================
+
+$ jid -r < users.json                 (select .users[*].name, press Enter)
+$ jid -0 < files.json | xargs -0 rm
+
+-r prints a string result without quotes and escapes, and an array of
+strings, numbers, booleans or nulls one value per line. -j does the same
+without the trailing newline; -0 terminates each value with NUL instead.
+Other results are printed as JSON.
+
+============ Print the query for another tool ====
+
+$ jid --query-syntax jq < users.json  (select .users[?active].name)
+[.users[] | select(.active | IN(false, null, "", [], {}) | not) | .name | values]
+
+Like -q, the query is printed instead of the result, translated to jq,
+jsonpath, jmespath or pointer (JSON Pointer). A query that uses something
+the target language has no equivalent for is an error, e.g. a pipe in
+JSONPath or a projection in a JSON Pointer.
+
+============ Output YAML =========================
+
+$ jid --output yaml < values.json
+
+The selected result is printed as YAML. Keys keep the order of the input
+and multi-line strings become block scalars. CTRL-Y previews the current
+result as YAML inside jid.
+
+============ Output Go / TypeScript types ========
+
+$ jid --output go < response.json     (select .data.users, press Enter)
+$ jid --output ts < response.json
+
+Type definitions are inferred from the selected value. Nested objects
+become named types, keys missing from some array elements become optional
+fields and Go struct tags keep the original key names. F5 and F6 preview
+the Go and TypeScript types inside jid.
+
+============ Output a JSON Schema ================
+
+$ jid --output schema < orders.json > orders.schema.json
+
+A draft 2020-12 JSON Schema is inferred from the selected value. Array
+elements are merged, keys missing from some objects are not required and
+string fields with a few repeating values become enums. F7 previews the
+schema inside jid.
+
+============ Output CSV / TSV ====================
+
+$ jid --output csv < users.json       (select .users, press Enter)
+$ jid --output tsv --columns id,name,email < users.json
+
+An array of objects is printed with a header row and one row per element.
+The columns are the keys of all elements in the order they first appear,
+or the ones given with --columns. Nested arrays and objects are written as
+JSON in the cell. TSV escapes tabs and line breaks as \t, \n and \r.
+
+============ Canonical JSON ======================
+
+$ jid --canonical < response.json | sha256sum
+
+The result is printed in the RFC 8785 canonical form: compact, keys sorted
+and numbers and strings written one way only, so equal results give equal
+bytes. Numbers are IEEE 754 doubles; larger integers lose precision.
+
+============ Format with a template ==============
+
+$ jid --template '{{range .}}{{.name}}{{"\t"}}{{.id}}{{"\n"}}{{end}}' < users.json
+$ jid --template-file report.tmpl < users.json
+
+The result is printed through a Go text/template instead of as JSON.
+Besides the builtins, templates can use:
+
+  json          {{json .}} compact JSON
+  yaml          {{yaml .}} a YAML document
+  join          {{.tags | join ", "}} joins the elements of an array
+  pad           {{.name | pad 20}} pads to a width (negative: right aligned)
+  default       {{.email | default "-"}} for a missing, null or empty value
+  num / int     {{if gt (num .price) 9.5}} / {{if gt (int .age) 30}} compare numbers
 
 ============ With a JSON filter mode =============
 
@@ -139,6 +614,37 @@ CTRL-P
 CTRL-L
   Toggle view mode: full JSON or keys-only (objects only).
 
+CTRL-O
+  Switch to the next file given with -f (the query is kept).
+
+CTRL-R
+  Run the --cmd command again (the query is kept).
+
+CTRL-D
+  Decode JSON objects and arrays held in string values, so queries can
+  continue into them (e.g. .Records[0].body.orderId). Press again to
+  show the document as loaded. A single value can also be decoded in
+  the query: .Records[0].body | from_json(@).orderId
+
+CTRL-Y
+  Show the current result as YAML. Press again to go back.
+
+F5 / F6
+  Show Go / TypeScript types for the current result. Press again to go back.
+
+F7
+  Show a JSON Schema inferred from the current result. Press again to go back.
+
+F8 / F9 / F10
+  Copy the result / the query / the path of the highlighted key to the
+  clipboard with OSC 52. jid stays open. Works over SSH; inside tmux set
+  allow-passthrough (tmux 3.3+) or set-clipboard on.
+
+CTRL-V
+  Show the string under the query decoded: a JWT as header and claims
+  (exp / iat / nbf as readable times), other base64 / base64url data as
+  text, or as a hex dump when it is binary. Press again to go back.
+
 ESC
   Hide the candidate list.
 
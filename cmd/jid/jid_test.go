@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/simeji/jid"
@@ -21,14 +23,14 @@ func TestJidRun(t *testing.T) {
 	var assert = assert.New(t)
 
 	e := &EngineMock{err: nil}
-	result := run(e, false)
+	result := run(e, outputOption{})
 	assert.Zero(result)
 	assert.Equal(2, called)
 
-	result = run(e, true)
+	result = run(e, outputOption{queryMode: true})
 	assert.Equal(1, called)
 
-	result = run(e, false)
+	result = run(e, outputOption{})
 	assert.Zero(result)
 }
 
@@ -36,7 +38,7 @@ func TestJidRunWithError(t *testing.T) {
 	called = 0
 	var assert = assert.New(t)
 	e := &EngineMock{err: fmt.Errorf("")}
-	result := run(e, false)
+	result := run(e, outputOption{})
 	assert.Equal(2, result)
 	assert.Equal(0, called)
 }
@@ -63,3 +65,122 @@ func (e *EngineResultMock) GetContent() string {
 func (e *EngineResultMock) GetError() error {
 	return e.err
 }
+
+func TestJidRunWithRawOutput(t *testing.T) {
+	var assert = assert.New(t)
+
+	var b bytes.Buffer
+	stdout = &b
+	defer func() { stdout = os.Stdout }()
+
+	e := &EngineMock{err: nil}
+	assert.Zero(run(e, outputOption{raw: true}))
+	assert.Equal("{\"test\":\"result\"}\n", b.String())
+
+	b.Reset()
+	run(e, outputOption{})
+	assert.Equal(`{"test":"result"}`, b.String())
+}
+
+func TestJidRunWithQuerySyntax(t *testing.T) {
+	var assert = assert.New(t)
+
+	var b bytes.Buffer
+	stdout = &b
+	defer func() { stdout = os.Stdout }()
+
+	e := &EngineMock{err: nil}
+	assert.Zero(run(e, outputOption{queryMode: true, syntax: jid.QuerySyntaxJSONPath}))
+	assert.Equal("$.querystring", b.String())
+
+	b.Reset()
+	assert.Zero(run(e, outputOption{queryMode: true, syntax: jid.QuerySyntaxPointer}))
+	assert.Equal("/querystring", b.String())
+}
+
+func TestFormatOutput(t *testing.T) {
+	var assert = assert.New(t)
+
+	raw := outputOption{raw: true}
+	assert.Equal("a \"quoted\"\tvalue\n", formatOutput(`"a \"quoted\"\tvalue"`, raw))
+	assert.Equal("web\n1.50\ntrue\nnull\n", formatOutput(`["web", 1.50, true, null]`, raw))
+	assert.Equal("12345678901234567890\n", formatOutput(`12345678901234567890`, raw))
+	assert.Equal("[\"a\",{\"b\":1}]\n", formatOutput(`["a",{"b":1}]`, raw))
+	assert.Equal("{\n  \"a\": 1\n}\n", formatOutput("{\n  \"a\": 1\n}", raw))
+	assert.Equal("", formatOutput(`[]`, raw))
+	// CTRL-C leaves no content; nothing must reach xargs -0
+	assert.Equal("", formatOutput("", raw))
+	assert.Equal("", formatOutput("", outputOption{nul: true}))
+
+	assert.Equal("a\nb", formatOutput(`["a","b"]`, outputOption{join: true}))
+	assert.Equal("value", formatOutput(`"value"`, outputOption{join: true}))
+	assert.Equal("a b\x00c\x00", formatOutput(`["a b","c"]`, outputOption{nul: true}))
+	assert.Equal("a b\x00c", formatOutput(`["a b","c"]`, outputOption{nul: true, join: true}))
+
+	assert.Equal(`"value"`, formatOutput(`"value"`, outputOption{}))
+}
+
+func TestParseDelimiter(t *testing.T) {
+	var assert = assert.New(t)
+
+	d, err := parseDelimiter("")
+	assert.Nil(err)
+	assert.Equal(rune(0), d)
+
+	d, _ = parseDelimiter(`\t`)
+	assert.Equal('\t', d)
+
+	d, _ = parseDelimiter(";")
+	assert.Equal(';', d)
+
+	_, err = parseDelimiter(";;")
+	assert.NotNil(err)
+	_, err = parseDelimiter(`"`)
+	assert.NotNil(err)
+}
+
+func TestSplitList(t *testing.T) {
+	var assert = assert.New(t)
+
+	assert.Nil(splitList(""))
+	assert.Equal([]string{"item", "dependency"}, splitList(" item, ,dependency"))
+}
+
+func TestProgressPrinter(t *testing.T) {
+	var assert = assert.New(t)
+
+	var b bytes.Buffer
+	p := progressPrinter(&b)
+	p(10, 200)
+	p(11, 200)
+	p(100, 200)
+	assert.Equal("\rindexing 5%\rindexing 50%", b.String())
+
+	b.Reset()
+	p(200, 200)
+	assert.Equal("\r\033[K", b.String())
+}
+
+func TestParseTemplate(t *testing.T) {
+	var assert = assert.New(t)
+
+	tmpl, err := parseTemplate("", "")
+	assert.Nil(err)
+	assert.Nil(tmpl)
+
+	tmpl, err = parseTemplate("{{.name}}", "")
+	assert.Nil(err)
+	assert.Equal("template", tmpl.Name())
+
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	_ = os.WriteFile(path, []byte("{{range .}}{{.}}{{end}}"), 0644)
+	tmpl, err = parseTemplate("", path)
+	assert.Nil(err)
+	assert.Equal("report.tmpl", tmpl.Name())
+
+	_, err = parseTemplate("{{.name}}", path)
+	assert.EqualError(err, "--template and --template-file cannot be used together")
+
+	_, err = parseTemplate("{{.name", "")
+	assert.NotNil(err)
+}
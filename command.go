@@ -0,0 +1,62 @@
+package jid
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CommandRun describes one execution of the input command.
+type CommandRun struct {
+	Time     time.Time
+	ExitCode int
+}
+
+// shellCommand returns the command that runs line with the system shell.
+func shellCommand(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
+
+// NewJsonManagerFromCommand runs command with the system shell and decodes its
+// stdout as described by opt. The returned run is valid whenever the command
+// could be started, even when its output does not parse.
+func NewJsonManagerFromCommand(command string, opt *InputOption) (*JsonManager, *CommandRun, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	run := &CommandRun{Time: time.Now()}
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, nil, errors.Wrap(err, command)
+		}
+		run.ExitCode = exitErr.ExitCode()
+	}
+
+	// Some tools print a usable document and still exit non-zero (e.g.
+	// docker inspect with one unknown name), so the output decides.
+	jm, err := NewJsonManagerWithOption(&stdout, opt)
+	if err != nil {
+		if run.ExitCode != 0 {
+			msg := strings.TrimSpace(stderr.String())
+			if i := strings.IndexByte(msg, '\n'); i >= 0 {
+				msg = msg[:i]
+			}
+			if msg == "" {
+				return nil, run, errors.Errorf("%s: exit status %d", command, run.ExitCode)
+			}
+			return nil, run, errors.Errorf("%s: exit status %d: %s", command, run.ExitCode, msg)
+		}
+		return nil, run, errors.Wrap(err, command)
+	}
+	return jm, run, nil
+}
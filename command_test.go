@@ -0,0 +1,36 @@
+package jid
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJsonManagerFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	var assert = assert.New(t)
+
+	jm, run, err := NewJsonManagerFromCommand(`printf '{"items":[{"name":"web"}]}'`, &InputOption{})
+	assert.Nil(err)
+	assert.Equal(0, run.ExitCode)
+	assert.False(run.Time.IsZero())
+	result, _, _, _ := jm.Get(NewQueryWithString(".items[0].name"), true)
+	assert.Equal(`"web"`, result)
+
+	// the output decides, not the exit status
+	jm, run, err = NewJsonManagerFromCommand(`echo '[1]'; exit 3`, &InputOption{})
+	assert.Nil(err)
+	assert.Equal(3, run.ExitCode)
+	assert.NotNil(jm)
+
+	_, run, err = NewJsonManagerFromCommand(`echo 'not found' >&2; exit 1`, &InputOption{})
+	assert.Equal(1, run.ExitCode)
+	assert.Regexp("exit status 1: not found", err.Error())
+
+	_, run, err = NewJsonManagerFromCommand(`echo '{'`, &InputOption{})
+	assert.Equal(0, run.ExitCode)
+	assert.Regexp("invalid json format", err.Error())
+}
@@ -56,6 +56,17 @@ type KeybindingsConfig struct {
 	CursorToStart  string `toml:"cursor_to_start"`
 	CursorToEnd    string `toml:"cursor_to_end"`
 	ToggleFuncHelp string `toml:"toggle_func_help"`
+	SwitchFile     string `toml:"switch_file"`          // cycle through files given with -f
+	RerunCommand   string `toml:"rerun_command"`        // run the --cmd command again
+	EmbeddedJSON   string `toml:"toggle_embedded_json"` // decode JSON held in strings
+	DecodeValue    string `toml:"decode_value"`         // JWT / base64 view of the result
+	PreviewYAML    string `toml:"preview_yaml"`         // YAML view of the result
+	PreviewGo      string `toml:"preview_go"`           // Go types of the result
+	PreviewTS      string `toml:"preview_ts"`           // TypeScript types of the result
+	PreviewSchema  string `toml:"preview_schema"`       // JSON Schema of the result
+	CopyResult     string `toml:"copy_result"`          // OSC 52 copy of the result
+	CopyQuery      string `toml:"copy_query"`           // OSC 52 copy of the query
+	CopyPath       string `toml:"copy_path"`            // OSC 52 copy of the highlighted path
 	Quit           string `toml:"quit"`
 }
 
@@ -84,6 +95,17 @@ func defaultConfig() Config {
 			CursorToStart:  "ctrl+a",
 			CursorToEnd:    "ctrl+e",
 			ToggleFuncHelp: "ctrl+x",
+			SwitchFile:     "ctrl+o",
+			RerunCommand:   "ctrl+r",
+			EmbeddedJSON:   "ctrl+d",
+			DecodeValue:    "ctrl+v",
+			PreviewYAML:    "ctrl+y",
+			PreviewGo:      "f5",
+			PreviewTS:      "f6",
+			PreviewSchema:  "f7",
+			CopyResult:     "f8",
+			CopyQuery:      "f9",
+			CopyPath:       "f10",
 			Quit:           "ctrl+q",
 		},
 	}
@@ -199,6 +221,39 @@ func mergeKeybindings(dst *KeybindingsConfig, src KeybindingsConfig) {
 	if src.ToggleFuncHelp != "" {
 		dst.ToggleFuncHelp = src.ToggleFuncHelp
 	}
+	if src.SwitchFile != "" {
+		dst.SwitchFile = src.SwitchFile
+	}
+	if src.RerunCommand != "" {
+		dst.RerunCommand = src.RerunCommand
+	}
+	if src.EmbeddedJSON != "" {
+		dst.EmbeddedJSON = src.EmbeddedJSON
+	}
+	if src.DecodeValue != "" {
+		dst.DecodeValue = src.DecodeValue
+	}
+	if src.PreviewYAML != "" {
+		dst.PreviewYAML = src.PreviewYAML
+	}
+	if src.PreviewGo != "" {
+		dst.PreviewGo = src.PreviewGo
+	}
+	if src.PreviewTS != "" {
+		dst.PreviewTS = src.PreviewTS
+	}
+	if src.PreviewSchema != "" {
+		dst.PreviewSchema = src.PreviewSchema
+	}
+	if src.CopyResult != "" {
+		dst.CopyResult = src.CopyResult
+	}
+	if src.CopyQuery != "" {
+		dst.CopyQuery = src.CopyQuery
+	}
+	if src.CopyPath != "" {
+		dst.CopyPath = src.CopyPath
+	}
 	if src.Quit != "" {
 		dst.Quit = src.Quit
 	}
@@ -20,6 +20,17 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, "ctrl+j", cfg.Keybindings.ScrollDown)
 	assert.Equal(t, "ctrl+k", cfg.Keybindings.ScrollUp)
 	assert.Equal(t, "ctrl+x", cfg.Keybindings.ToggleFuncHelp)
+	assert.Equal(t, "ctrl+o", cfg.Keybindings.SwitchFile)
+	assert.Equal(t, "ctrl+r", cfg.Keybindings.RerunCommand)
+	assert.Equal(t, "ctrl+d", cfg.Keybindings.EmbeddedJSON)
+	assert.Equal(t, "ctrl+v", cfg.Keybindings.DecodeValue)
+	assert.Equal(t, "ctrl+y", cfg.Keybindings.PreviewYAML)
+	assert.Equal(t, "f5", cfg.Keybindings.PreviewGo)
+	assert.Equal(t, "f6", cfg.Keybindings.PreviewTS)
+	assert.Equal(t, "f7", cfg.Keybindings.PreviewSchema)
+	assert.Equal(t, "f8", cfg.Keybindings.CopyResult)
+	assert.Equal(t, "f9", cfg.Keybindings.CopyQuery)
+	assert.Equal(t, "f10", cfg.Keybindings.CopyPath)
 }
 
 func TestLoadConfigMissingFile(t *testing.T) {
@@ -0,0 +1,136 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	reBase64 = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+	// jwtTimeClaims are the registered claims holding NumericDate values.
+	jwtTimeClaims = []string{"exp", "iat", "nbf", "auth_time"}
+)
+
+// DecodeValue decodes a JWT or base64 / base64url string for display. The
+// second result is false when s is neither.
+func DecodeValue(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, binaryPrefix)
+	if strings.HasPrefix(strings.ToLower(s), "bearer ") {
+		s = strings.TrimSpace(s[len("bearer "):])
+	}
+	if out, ok := decodeJWT(s); ok {
+		return out, true
+	}
+	if b, ok := decodeBase64(s); ok {
+		return formatDecoded(b), true
+	}
+	return "", false
+}
+
+// decodeJWT renders the header and claims of a JWS compact serialization.
+// The signature is not verified.
+func decodeJWT(s string) (string, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	var header, claims map[string]interface{}
+	for i, v := range []*map[string]interface{}{&header, &claims} {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[i], "="))
+		if err != nil {
+			return "", false
+		}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(v); err != nil || *v == nil {
+			return "", false
+		}
+	}
+
+	for _, name := range jwtTimeClaims {
+		n, ok := claims[name].(json.Number)
+		if !ok {
+			continue
+		}
+		sec, err := n.Float64()
+		if err != nil {
+			continue
+		}
+		t := time.Unix(int64(sec), 0).UTC()
+		claims[name] = fmt.Sprintf("%s (%s)", n, t.Format(time.RFC3339))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("JWT header:\n")
+	sb.WriteString(prettyJSON(header))
+	sb.WriteString("\n\nJWT claims:\n")
+	sb.WriteString(prettyJSON(claims))
+	sig, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err == nil {
+		fmt.Fprintf(&sb, "\n\nsignature: %d bytes (not verified)", len(sig))
+	}
+	return sb.String(), true
+}
+
+// decodeBase64 accepts standard and URL-safe base64, padded or not.
+func decodeBase64(s string) ([]byte, bool) {
+	if len(s) < 4 || !reBase64.MatchString(s) {
+		return nil, false
+	}
+	raw := strings.TrimRight(s, "=")
+	if strings.ContainsAny(raw, "-_") {
+		b, err := base64.RawURLEncoding.DecodeString(raw)
+		return b, err == nil
+	}
+	b, err := base64.RawStdEncoding.DecodeString(raw)
+	return b, err == nil
+}
+
+// formatDecoded shows JSON pretty printed, text as is and anything else as a
+// hex dump.
+func formatDecoded(b []byte) string {
+	if json.Valid(b) {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if d.Decode(&v) == nil {
+			return prettyJSON(v)
+		}
+	}
+	if isText(b) {
+		return string(b)
+	}
+	return strings.TrimRight(hex.Dump(b), "\n")
+}
+
+// isText reports whether b is UTF-8 without control characters other than
+// whitespace.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func prettyJSON(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+	return strings.TrimRight(buf.String(), "\n")
+}
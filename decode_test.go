@@ -0,0 +1,62 @@
+package jid
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeValueJWT(t *testing.T) {
+	var assert = assert.New(t)
+
+	enc := base64.RawURLEncoding.EncodeToString
+	token := enc([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc([]byte(`{"sub":"1234","exp":1700000000,"iat":1699996400,"roles":["admin"]}`)) + "." +
+		enc([]byte("0123456789abcdef0123456789abcdef"))
+
+	out, ok := DecodeValue(token)
+	assert.True(ok)
+	assert.Equal(`JWT header:
+{
+  "alg": "HS256",
+  "typ": "JWT"
+}
+
+JWT claims:
+{
+  "exp": "1700000000 (2023-11-14T22:13:20Z)",
+  "iat": "1699996400 (2023-11-14T21:13:20Z)",
+  "roles": [
+    "admin"
+  ],
+  "sub": "1234"
+}
+
+signature: 32 bytes (not verified)`, out)
+
+	bearer, ok := DecodeValue("Bearer " + token)
+	assert.True(ok)
+	assert.Equal(out, bearer)
+}
+
+func TestDecodeValueBase64(t *testing.T) {
+	var assert = assert.New(t)
+
+	out, ok := DecodeValue(base64.StdEncoding.EncodeToString([]byte("user:pa55word\n")))
+	assert.True(ok)
+	assert.Equal("user:pa55word\n", out)
+
+	out, ok = DecodeValue(base64.RawURLEncoding.EncodeToString([]byte(`{"a":[1,2]}`)))
+	assert.True(ok)
+	assert.Equal("{\n  \"a\": [\n    1,\n    2\n  ]\n}", out)
+
+	out, ok = DecodeValue("base64:" + base64.StdEncoding.EncodeToString([]byte{0x00, 0xff, 0x10, 'A'}))
+	assert.True(ok)
+	assert.Equal("00000000  00 ff 10 41                                       |...A|", out)
+
+	for _, s := range []string{"", "abc", "hello world", "a.b.c", "not base64!"} {
+		_, ok = DecodeValue(s)
+		assert.False(ok, s)
+	}
+}
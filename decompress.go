@@ -0,0 +1,70 @@
+package jid
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// decompress returns the decompressed contents of buf when its leading bytes
+// identify a gzip, bzip2 or zlib stream, and buf unchanged otherwise.
+func decompress(buf []byte) ([]byte, error) {
+	switch {
+	case isGzip(buf):
+		zr, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gzip data")
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gzip data")
+		}
+		return out, nil
+	case isBzip2(buf):
+		out, err := io.ReadAll(bzip2.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bzip2 data")
+		}
+		return out, nil
+	case isZlib(buf):
+		// A zlib header is only two bytes and could start plain text, so a
+		// stream that fails to inflate is treated as uncompressed input.
+		zr, err := zlib.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return buf, nil
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			return buf, nil
+		}
+		return out, nil
+	}
+	return buf, nil
+}
+
+func isGzip(buf []byte) bool {
+	return len(buf) >= 2 && buf[0] == 0x1f && buf[1] == 0x8b
+}
+
+func isBzip2(buf []byte) bool {
+	return len(buf) >= 4 && string(buf[:3]) == "BZh" && '1' <= buf[3] && buf[3] <= '9'
+}
+
+// isZlib matches the headers zlib writes with the default 32K window
+// (0x78 followed by the FLG byte of one of the compression levels).
+func isZlib(buf []byte) bool {
+	if len(buf) < 2 || buf[0] != 0x78 {
+		return false
+	}
+	switch buf[1] {
+	case 0x01, 0x5e, 0x9c, 0xda:
+		return true
+	}
+	return false
+}
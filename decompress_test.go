@@ -0,0 +1,68 @@
+package jid
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bzip2 compressed `{"a":1}`; the standard library has no bzip2 writer.
+const bzip2Data = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x3a\xdf\x03\x60\x00\x00\x02\x99\x80\x10\x00\x20\x10\x20\x00\x00\x0a\x20\x00\x21\x80\x0c\x02\x5b\x06\xdc\x5d\xc9\x14\xe1\x42\x40\xeb\x7c\x0d\x80"
+
+func TestDecompress(t *testing.T) {
+	var assert = assert.New(t)
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte(`{"a":1}`))
+	_ = gw.Close()
+	out, err := decompress(gz.Bytes())
+	assert.Nil(err)
+	assert.Equal(`{"a":1}`, string(out))
+
+	var zl bytes.Buffer
+	zw := zlib.NewWriter(&zl)
+	_, _ = zw.Write([]byte(`{"a":1}`))
+	_ = zw.Close()
+	out, err = decompress(zl.Bytes())
+	assert.Nil(err)
+	assert.Equal(`{"a":1}`, string(out))
+
+	out, err = decompress([]byte(bzip2Data))
+	assert.Nil(err)
+	assert.Equal(`{"a":1}`, string(out))
+
+	// uncompressed input, including text that starts like a zlib header
+	out, err = decompress([]byte(`{"a":1}`))
+	assert.Nil(err)
+	assert.Equal(`{"a":1}`, string(out))
+	out, err = decompress([]byte("x^: 1\n"))
+	assert.Nil(err)
+	assert.Equal("x^: 1\n", string(out))
+
+	_, err = decompress([]byte("\x1f\x8b\x08\x00broken"))
+	assert.Regexp("invalid gzip data", err.Error())
+}
+
+func TestNewJsonManagerWithCompressedInput(t *testing.T) {
+	var assert = assert.New(t)
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte("{\"a\":1}\n{\"a\":2}\n"))
+	_ = gw.Close()
+
+	jm, err := NewJsonManager(&gz)
+	assert.Nil(err)
+	assert.Equal(InputFormatLines, jm.Format())
+	result, _, _, _ := jm.Get(NewQueryWithString(".[1].a"), true)
+	assert.Equal(`2`, result)
+
+	jm, err = NewJsonManager(bytes.NewBufferString(bzip2Data))
+	assert.Nil(err)
+	result, _, _, _ = jm.Get(NewQueryWithString(".a"), true)
+	assert.Equal(`1`, result)
+}
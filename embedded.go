@@ -0,0 +1,157 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	simplejson "github.com/bitly/go-simplejson"
+	"github.com/pkg/errors"
+)
+
+// reFromJSON matches the from_json(@) stage of a query. go-jmespath has no
+// way to register functions, so the stage is evaluated by jid itself.
+var reFromJSON = regexp.MustCompile(`\|\s*from_json\(@\)`)
+
+// decodeEmbedded returns the JSON document held by s, or false when s does
+// not hold an object or array. Numbers keep their precision.
+func decodeEmbedded(s string) (interface{}, bool) {
+	t := strings.TrimSpace(s)
+	if t == "" || (t[0] != '{' && t[0] != '[') || !json.Valid([]byte(t)) {
+		return nil, false
+	}
+	d := json.NewDecoder(strings.NewReader(t))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// expandEmbedded returns a copy of v in which every string holding a JSON
+// object or array is replaced by the decoded value, recursively. When order
+// is not nil, objects are copied as orderedObjects in document order.
+func expandEmbedded(v interface{}, order keyOrder) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if order != nil {
+			o := newOrderedObject()
+			for _, k := range order.keys(t) {
+				o.set(k, expandEmbedded(t[k], order))
+			}
+			return o
+		}
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = expandEmbedded(e, order)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = expandEmbedded(e, order)
+		}
+		return a
+	case string:
+		if d, ok := decodeEmbedded(t); ok {
+			if order != nil {
+				return expandEmbedded(d, scanKeyOrder([]byte(t), d))
+			}
+			return expandEmbedded(d, nil)
+		}
+	}
+	return v
+}
+
+// fromJSON implements from_json(@): a string is decoded (null when it is not
+// JSON) and an array is decoded element by element.
+func fromJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		var d interface{}
+		dec := json.NewDecoder(strings.NewReader(t))
+		dec.UseNumber()
+		if err := dec.Decode(&d); err != nil || dec.More() {
+			return nil
+		}
+		return d
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = fromJSON(e)
+		}
+		return a
+	}
+	return v
+}
+
+// newJsonManagerFromValue builds a manager over an already decoded value.
+// keepOrder is passed on to newJsonManager.
+func newJsonManagerFromValue(v interface{}, keepOrder bool) (*JsonManager, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, errors.Wrap(err, "failure json encode")
+	}
+	return newJsonManager(&decodedInput{data: buf.Bytes(), format: InputFormatJSON}, keepOrder)
+}
+
+// SetExpandEmbedded switches between the document as loaded and a view in
+// which JSON objects and arrays encoded in string values are decoded in place.
+func (jm *JsonManager) SetExpandEmbedded(on bool) {
+	if jm.lazy != nil {
+		jm.lazy.expand = on
+		return
+	}
+	if on == jm.expanded {
+		return
+	}
+	if on {
+		if jm.embedded == nil {
+			e, err := newJsonManagerFromValue(expandEmbedded(jm.origin.Interface(), jm.order), jm.order != nil)
+			if err != nil {
+				return
+			}
+			jm.embedded = e
+		}
+		jm.raw, jm.rawData = jm.origin, jm.originData
+		jm.origin, jm.current, jm.originData = jm.embedded.origin, jm.embedded.origin, jm.embedded.originData
+	} else {
+		jm.origin, jm.current, jm.originData = jm.raw, jm.raw, jm.rawData
+	}
+	jm.expanded = on
+}
+
+// ExpandEmbedded reports whether embedded JSON strings are decoded.
+func (jm *JsonManager) ExpandEmbedded() bool {
+	if jm.lazy != nil {
+		return jm.lazy.expand
+	}
+	return jm.expanded
+}
+
+// getFilteredDataFromJSON evaluates qs = base | from_json(@) rest by running
+// base, decoding its result and evaluating rest against the decoded value.
+func (jm *JsonManager) getFilteredDataFromJSON(qs string, loc []int, confirm bool) (*simplejson.Json, []string, []string, error) {
+	base := strings.TrimRight(qs[:loc[0]], " ")
+	if base == "" {
+		base = "."
+	}
+	j, _, _, err := jm.GetFilteredData(NewQueryWithString(base), true)
+	if err != nil {
+		return j, []string{"", ""}, []string{}, err
+	}
+
+	sub, err := newJsonManagerFromValue(fromJSON(j.Interface()), false)
+	if err != nil {
+		return j, []string{"", ""}, []string{}, err
+	}
+	rest := qs[loc[1]:]
+	if !strings.HasPrefix(rest, ".") {
+		rest = "." + rest
+	}
+	return sub.GetFilteredData(NewQueryWithString(rest), confirm)
+}
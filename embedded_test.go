@@ -0,0 +1,87 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const embeddedFixture = `{"Records":[` +
+	`{"body":"{\"orderId\":12345678901234567890,\"items\":[{\"sku\":\"A-1\"}],\"meta\":\"{\\\"source\\\":\\\"web\\\"}\"}"},` +
+	`{"body":" [1, 2] "},` +
+	`{"body":"plain text"}]}`
+
+func TestExpandEmbedded(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManager(bytes.NewBufferString(embeddedFixture))
+	assert.Nil(err)
+	assert.False(jm.ExpandEmbedded())
+
+	result, _, _, _ := jm.Get(NewQueryWithString(".Records[0].body.orderId"), true)
+	assert.Equal("null", result)
+
+	jm.SetExpandEmbedded(true)
+	assert.True(jm.ExpandEmbedded())
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[0].body.orderId"), true)
+	assert.Equal("12345678901234567890", result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[0].body.meta.source"), true)
+	assert.Equal(`"web"`, result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[*].body.items[0].sku"), true)
+	assert.Equal(`["A-1"]`, result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[1].body"), true)
+	assert.Equal(`[1,2]`, result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[2].body"), true)
+	assert.Equal(`"plain text"`, result)
+
+	_, _, candidates, _ := jm.Get(NewQueryWithString(".Records[0].body."), false)
+	assert.Equal([]string{"items", "meta", "orderId"}, candidates)
+	j, _, _, _ := jm.GetFilteredData(NewQueryWithString(".Records[0].body"), true)
+	assert.Equal([]string{"items", "meta", "orderId"}, NewSuggestion().GetCandidateKeys(j, ""))
+
+	jm.SetExpandEmbedded(false)
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[2].body"), true)
+	assert.Equal(`"plain text"`, result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[1].body"), true)
+	assert.Equal(`" [1, 2] "`, result)
+}
+
+func TestFromJSONOperator(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManager(bytes.NewBufferString(embeddedFixture))
+	assert.Nil(err)
+
+	result, _, _, err := jm.Get(NewQueryWithString(".Records[0].body | from_json(@).orderId"), true)
+	assert.Nil(err)
+	assert.Equal("12345678901234567890", result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[0].body | from_json(@) | sort(keys(@))"), true)
+	assert.Equal(`["items","meta","orderId"]`, result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[0].body | from_json(@).meta | from_json(@).source"), true)
+	assert.Equal(`"web"`, result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".Records[*].body | from_json(@)"), true)
+	assert.Equal(`[{"items":[{"sku":"A-1"}],"meta":"{\"source\":\"web\"}","orderId":12345678901234567890},[1,2],null]`, result)
+
+	_, _, candidates, _ := jm.Get(NewQueryWithString(".Records[0].body | from_json(@).it"), false)
+	assert.Equal([]string{"items"}, candidates)
+
+	assert.Contains(NewSuggestion().GetFunctionCandidatesFiltered("from", STRING), "from_json(")
+}
+
+func TestLazyExpandEmbedded(t *testing.T) {
+	var assert = assert.New(t)
+
+	path := writeLazyFixture(t, embeddedFixture)
+	jm, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true})
+	assert.Nil(err)
+	defer jm.Close()
+
+	jm.SetExpandEmbedded(true)
+	assert.True(jm.ExpandEmbedded())
+	result, _, _, _ := jm.Get(NewQueryWithString(".Records[0].body.items[0].sku"), true)
+	assert.Equal(`"A-1"`, result)
+}
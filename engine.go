@@ -1,11 +1,13 @@
 package jid
 
 import (
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
 
 	termbox "github.com/nsf/termbox-go"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -13,6 +15,35 @@ const (
 	FilterPrompt string = "[Filter]> "
 )
 
+// previewMode selects an alternative rendering of the current result. Each
+// mode is toggled by its own key and stays on while the query is edited.
+type previewMode int
+
+const (
+	previewNone previewMode = iota
+	previewDecode
+	previewYAML
+	previewGo
+	previewTS
+	previewSchema
+)
+
+var previewLabels = map[previewMode]string{
+	previewDecode: "[decoded]",
+	previewYAML:   "[yaml]",
+	previewGo:     "[go types]",
+	previewTS:     "[ts types]",
+	previewSchema: "[schema]",
+}
+
+// previewFormats are the output formats the preview modes render with.
+var previewFormats = map[previewMode]OutputFormat{
+	previewYAML:   OutputYAML,
+	previewGo:     OutputGo,
+	previewTS:     OutputTS,
+	previewSchema: OutputSchema,
+}
+
 type EngineInterface interface {
 	Run() EngineResultInterface
 	GetQuery() QueryInterface
@@ -26,6 +57,8 @@ type EngineResultInterface interface {
 
 type Engine struct {
 	manager        *JsonManager
+	managers       []*JsonManager // all loaded files; manager is managers[managerIdx]
+	managerIdx     int
 	query          QueryInterface
 	queryCursorIdx int
 	term           *Terminal
@@ -56,21 +89,76 @@ type Engine struct {
 	candidateScrollNeeded bool
 	// quit requested via quit keybinding
 	quitRequested bool
+	// watch mode: files are reloaded when they change on disk
+	paths     []string
+	input     InputOption
+	watcher   *watcher
+	reloaded  bool
+	reloadErr error
+	// command input: re-run with the rerun_command key
+	command    string
+	commandRun *CommandRun
+	// decode JSON embedded in string values (toggle_embedded_json)
+	expandEmbedded bool
+	preview        previewMode
+	output         OutputOption
+	// copy keys: clipboard receives the copied text and message confirms it
+	// in the status until the next key
+	clipboard func(string) error
+	message   string
 }
 
 type EngineAttribute struct {
 	DefaultQuery string
 	Monochrome   bool
 	PrettyResult bool
+	Input        InputOption
+	// Files are loaded by path instead of reading the io.Reader passed to
+	// NewEngine. With several files the switch_file key cycles through them.
+	Files []string
+	// Watch reloads Files when they change on disk and re-runs the query.
+	Watch bool
+	// Command is run with the system shell and its stdout is loaded instead
+	// of the io.Reader. The rerun_command key runs it again.
+	Command string
+	// Output is how the result is printed when jid exits.
+	Output OutputOption
 }
 
 func NewEngine(s io.Reader, ea *EngineAttribute) (EngineInterface, error) {
-	j, err := NewJsonManager(s)
-	if err != nil {
-		return nil, err
+	if ea.Watch && len(ea.Files) == 0 {
+		return nil, errors.New("watch mode needs files given by path")
+	}
+	if f := ea.Output.Format; f != "" && f != OutputJSON && f != OutputCanonical {
+		// only output in other formats shows the key order of the input
+		ea.Input.KeyOrder = true
+	}
+	var managers []*JsonManager
+	var run *CommandRun
+	if ea.Command != "" {
+		j, r, err := NewJsonManagerFromCommand(ea.Command, &ea.Input)
+		if err != nil {
+			return nil, err
+		}
+		managers, run = append(managers, j), r
+	} else if len(ea.Files) > 0 {
+		for _, path := range ea.Files {
+			j, err := NewJsonManagerFromFile(path, &ea.Input)
+			if err != nil {
+				return nil, err
+			}
+			managers = append(managers, j)
+		}
+	} else {
+		j, err := NewJsonManagerWithOption(s, &ea.Input)
+		if err != nil {
+			return nil, err
+		}
+		managers = append(managers, j)
 	}
 	e := &Engine{
-		manager:       j,
+		manager:       managers[0],
+		managers:      managers,
 		term:          NewTerminal(FilterPrompt, DefaultY, ea.Monochrome),
 		query:         NewQuery([]rune(ea.DefaultQuery)),
 		complete:      []string{"", ""},
@@ -84,6 +172,15 @@ func NewEngine(s io.Reader, ea *EngineAttribute) (EngineInterface, error) {
 		showFuncHelp:     true,
 		placeholderStart: -1,
 		cfg:              LoadConfig(),
+		paths:            ea.Files,
+		input:            ea.Input,
+		command:          ea.Command,
+		commandRun:       run,
+		output:           ea.Output,
+		clipboard:        copyToClipboard,
+	}
+	if ea.Watch {
+		e.watcher = newWatcher(ea.Files)
 	}
 	e.history = NewHistory(e.cfg.HistoryPath(), e.cfg.History.MaxSize)
 	e.queryCursorIdx = e.query.Length()
@@ -119,6 +216,11 @@ func (e *Engine) Run() EngineResultInterface {
 	}
 	defer termbox.Close()
 
+	if e.watcher != nil {
+		e.watcher.start(termbox.Interrupt)
+		defer e.watcher.stop()
+	}
+
 	var contents []string
 	actionMap := e.buildActionMap(&contents)
 
@@ -130,10 +232,18 @@ func (e *Engine) Run() EngineResultInterface {
 		}
 
 		bl := len(contents)
+		_, height := termbox.Size()
+		follow := e.reloaded && e.manager.Format() == InputFormatLines && e.atBottom(bl, height)
 		contents = e.getContents()
 		e.setCandidateData()
 		e.queryConfirm = false
-		if bl != len(contents) {
+		if e.reloaded {
+			// keep the scroll position; follow appended lines like tail -f
+			e.reloaded = false
+			if follow {
+				e.followBottom(len(contents), height)
+			}
+		} else if bl != len(contents) {
 			e.contentOffset = 0
 		}
 
@@ -198,6 +308,7 @@ func (e *Engine) Run() EngineResultInterface {
 			PlaceholderLen:         e.placeholderLen,
 			SelectedCandidate:      selectedCandidate,
 			SelectedCandidateIndent: selectedCandidateIndent,
+			Status:                 e.status(),
 		}
 		err = e.term.Draw(ta)
 		if err != nil {
@@ -206,6 +317,7 @@ func (e *Engine) Run() EngineResultInterface {
 
 		switch ev := termbox.PollEvent(); ev.Type {
 		case termbox.EventKey:
+			e.message = ""
 			switch ev.Key {
 			case 0:
 				// Detect Shift+Tab (\x1b[Z) arriving as: KeyEsc → '[' → 'Z'.
@@ -255,13 +367,7 @@ func (e *Engine) Run() EngineResultInterface {
 				if e.candidatemode {
 					e.confirmCandidate()
 				} else if e.cfg.IsExitOnEnter() {
-					var cc string
-					var err error
-					if e.prettyResult {
-						cc, _, _, err = e.manager.GetPretty(e.query, true)
-					} else {
-						cc, _, _, err = e.manager.Get(e.query, true)
-					}
+					cc, err := e.resultContent()
 					e.history.Add(e.query.StringGet())
 					_ = e.history.Save()
 
@@ -277,13 +383,7 @@ func (e *Engine) Run() EngineResultInterface {
 				if fn, ok := actionMap[ev.Key]; ok {
 					fn()
 					if e.quitRequested {
-						var cc string
-						var err error
-						if e.prettyResult {
-							cc, _, _, err = e.manager.GetPretty(e.query, true)
-						} else {
-							cc, _, _, err = e.manager.Get(e.query, true)
-						}
+						cc, err := e.resultContent()
 						e.history.Add(e.query.StringGet())
 						_ = e.history.Save()
 						return &EngineResult{
@@ -294,6 +394,10 @@ func (e *Engine) Run() EngineResultInterface {
 					}
 				}
 			}
+		case termbox.EventInterrupt:
+			if e.watcher != nil {
+				e.reloadFiles(e.watcher.takeChanged())
+			}
 		case termbox.EventError:
 			panic(ev.Err)
 			break
@@ -302,18 +406,112 @@ func (e *Engine) Run() EngineResultInterface {
 	}
 }
 
+// resultContent renders the confirmed result in the output format.
+func (e *Engine) resultContent() (string, error) {
+	var cc string
+	var err error
+	switch {
+	case e.output.Format != OutputJSON && e.output.Format != "":
+		cc, err = e.manager.Render(e.query, &e.output, true)
+	case e.prettyResult:
+		cc, _, _, err = e.manager.GetPretty(e.query, true)
+	default:
+		cc, _, _, err = e.manager.Get(e.query, true)
+	}
+	return cc, err
+}
+
+// status returns the indicator shown at the right of the filter line.
+func (e *Engine) status() string {
+	var s []string
+	if name := e.manager.Name(); name != "" {
+		if len(e.managers) > 1 {
+			name = fmt.Sprintf("%s (%d/%d)", name, e.managerIdx+1, len(e.managers))
+		}
+		s = append(s, name)
+	}
+	if e.manager.Lazy() {
+		s = append(s, "[lazy]")
+	}
+	if e.expandEmbedded {
+		s = append(s, "[embedded json]")
+	}
+	if label, ok := previewLabels[e.preview]; ok {
+		s = append(s, label)
+	}
+	if e.commandRun != nil {
+		s = append(s, fmt.Sprintf("[ran %s, exit %d]", e.commandRun.Time.Format("15:04:05"), e.commandRun.ExitCode))
+	}
+	if e.reloadErr != nil {
+		s = append(s, "[reload failed]")
+	} else if e.watcher != nil {
+		s = append(s, "[watch]")
+	}
+	if e.manager.Lenient() {
+		s = append(s, "[lenient]")
+	}
+	if n := e.manager.Documents(); n == 1 {
+		s = append(s, "[1 doc]")
+	} else if n > 1 {
+		s = append(s, fmt.Sprintf("[%d docs]", n))
+	}
+	if e.message != "" {
+		s = append(s, e.message)
+	}
+	return strings.Join(s, " ")
+}
+
 func (e *Engine) getContents() []string {
 	var c string
 	var contents []string
 	c, e.complete, e.candidates, _ = e.manager.GetPretty(e.query, e.queryConfirm)
 	if e.keymode {
 		contents = e.candidates
+	} else if e.preview != previewNone {
+		contents = strings.Split(e.previewContents(), "\n")
 	} else {
 		contents = strings.Split(c, "\n")
 	}
 	return contents
 }
 
+// togglePreview switches the content view to mode, or back to JSON when mode
+// is already shown.
+func (e *Engine) togglePreview(mode previewMode) {
+	if e.preview == mode {
+		e.preview = previewNone
+	} else {
+		e.preview = mode
+	}
+	e.contentOffset = 0
+}
+
+// previewContents renders the current result in the active preview mode.
+func (e *Engine) previewContents() string {
+	j, _, _, err := e.manager.GetFilteredData(e.query, e.queryConfirm)
+	if err != nil {
+		return err.Error()
+	}
+	switch e.preview {
+	case previewDecode:
+		s, err := j.String()
+		if err != nil {
+			return "(the result is not a string)"
+		}
+		if out, ok := DecodeValue(s); ok {
+			return out
+		}
+		return "(not a JWT or base64 value)"
+	case previewYAML, previewGo, previewTS, previewSchema:
+		s, err := e.manager.Render(e.query, &OutputOption{Format: previewFormats[e.preview]}, e.queryConfirm)
+		if err != nil {
+			return err.Error()
+		}
+		return s
+	}
+	return ""
+}
+
 func (e *Engine) setCandidateData() {
 	l := len(e.candidates)
 	isFuncCandidates := l > 0 && strings.HasSuffix(e.candidates[0], "(")
@@ -697,6 +895,145 @@ func (e *Engine) ampFieldCursorPos(qs string) int {
 	return e.query.Length()
 }
 
+// switchFile makes the next loaded file the active document. The query is
+// kept so the same expression can be compared across files.
+func (e *Engine) switchFile() {
+	if len(e.managers) < 2 {
+		return
+	}
+	e.managerIdx = (e.managerIdx + 1) % len(e.managers)
+	e.manager = e.managers[e.managerIdx]
+	e.manager.SetExpandEmbedded(e.expandEmbedded)
+	e.contentOffset = 0
+}
+
+// reloadFiles reloads the files at the given indexes of e.managers. A file that
+// fails to load (e.g. while it is half written) keeps its previous content.
+func (e *Engine) reloadFiles(idx []int) {
+	for _, i := range idx {
+		j, err := NewJsonManagerFromFile(e.paths[i], &e.input)
+		if err != nil {
+			e.reloadErr = err
+			continue
+		}
+		e.reloadErr = nil
+		_ = e.managers[i].Close()
+		e.managers[i] = j
+		if i == e.managerIdx {
+			e.manager = j
+			e.manager.SetExpandEmbedded(e.expandEmbedded)
+			e.reloaded = true
+		}
+	}
+}
+
+// rerunCommand runs the input command again and shows its new output with the
+// current query. Output that fails to parse keeps the previous document.
+func (e *Engine) rerunCommand() {
+	if e.command == "" {
+		return
+	}
+	j, run, err := NewJsonManagerFromCommand(e.command, &e.input)
+	if run != nil {
+		e.commandRun = run
+	}
+	if err != nil {
+		e.reloadErr = err
+		return
+	}
+	e.reloadErr = nil
+	_ = e.manager.Close()
+	e.managers[e.managerIdx] = j
+	e.manager = j
+	e.manager.SetExpandEmbedded(e.expandEmbedded)
+	e.reloaded = true
+}
+
+// atBottom reports whether the last of rows lines is visible in a terminal
+// of the given height.
+func (e *Engine) atBottom(rows int, height int) bool {
+	return e.contentOffset+height-DefaultY >= rows
+}
+
+// followBottom scrolls so that the last screenful of rows is visible.
+func (e *Engine) followBottom(rows int, height int) {
+	e.contentOffset = 0
+	if o := rows - (height - DefaultY); o > 0 {
+		e.contentOffset = o
+	}
+}
+
+// toggleEmbeddedJSON switches the view between the document as loaded and one
+// in which JSON encoded in string values is decoded in place.
+func (e *Engine) toggleEmbeddedJSON() {
+	e.expandEmbedded = !e.expandEmbedded
+	e.manager.SetExpandEmbedded(e.expandEmbedded)
+}
+
+// copyText puts s on the clipboard and confirms it in the status.
+func (e *Engine) copyText(what string, s string) {
+	if err := e.clipboard(s); err != nil {
+		e.message = fmt.Sprintf("[copy failed: %s]", err)
+		return
+	}
+	e.message = fmt.Sprintf("[copied %s]", what)
+}
+
+// copyResult copies the current result as it would be printed on exit.
+func (e *Engine) copyResult() {
+	cc, err := e.resultContent()
+	if err != nil {
+		e.message = fmt.Sprintf("[copy failed: %s]", err)
+		return
+	}
+	e.copyText("result", cc)
+}
+
+func (e *Engine) copyQuery() {
+	e.copyText("query", e.query.StringGet())
+}
+
+func (e *Engine) copyPath() {
+	path, ok := e.cursorPath()
+	if !ok {
+		e.message = "[copy failed: no path for this query]"
+		return
+	}
+	if path == "" {
+		path = "."
+	}
+	e.copyText("path "+path, path)
+}
+
+// cursorPath returns the path of the key highlighted in the result, or of
+// the result itself when no key is highlighted.
+func (e *Engine) cursorPath() (string, bool) {
+	qs := e.query.StringGet()
+	key := ""
+	if e.candidatemode && !e.keymode && len(e.candidates) > 0 {
+		key = e.candidates[e.candidateidx%len(e.candidates)]
+	} else if !e.candidatemode && !e.keymode && e.complete[0] != "" && e.complete[1] != "" {
+		key = e.complete[1]
+	}
+	if key != "" && !strings.HasSuffix(key, "(") {
+		q := NewQuery([]rune(qs))
+		_, _ = q.PopKeyword()
+		base, ok := pathOfQuery(q.StringGet())
+		return base + "." + jidKey(key), ok
+	}
+	return pathOfQuery(qs)
+}
+
+// pathOfQuery is queryPath that tells the root ("") from a query that is not
+// a path.
+func pathOfQuery(qs string) (string, bool) {
+	if qs == "" || qs == "." {
+		return "", true
+	}
+	path := queryPath(qs)
+	return path, path != ""
+}
+
 func (e *Engine) setQuitRequested() {
 	e.quitRequested = true
 }
@@ -830,6 +1167,17 @@ func (e *Engine) buildActionMap(contents *[]string) map[termbox.Key]func() {
 		resolveKey(kb.DeleteLine, "ctrl+u"):     e.deleteLineQuery,
 		resolveKey(kb.DeleteWord, "ctrl+w"):     e.deleteWordBackward,
 		resolveKey(kb.CandidateNext, "tab"): e.tabAction,
+		resolveKey(kb.SwitchFile, "ctrl+o"):  e.switchFile,
+		resolveKey(kb.RerunCommand, "ctrl+r"): e.rerunCommand,
+		resolveKey(kb.EmbeddedJSON, "ctrl+d"): e.toggleEmbeddedJSON,
+		resolveKey(kb.DecodeValue, "ctrl+v"):  func() { e.togglePreview(previewDecode) },
+		resolveKey(kb.PreviewYAML, "ctrl+y"):  func() { e.togglePreview(previewYAML) },
+		resolveKey(kb.PreviewGo, "f5"):        func() { e.togglePreview(previewGo) },
+		resolveKey(kb.PreviewTS, "f6"):        func() { e.togglePreview(previewTS) },
+		resolveKey(kb.PreviewSchema, "f7"):    func() { e.togglePreview(previewSchema) },
+		resolveKey(kb.CopyResult, "f8"):       e.copyResult,
+		resolveKey(kb.CopyQuery, "f9"):        e.copyQuery,
+		resolveKey(kb.CopyPath, "f10"):        e.copyPath,
 		resolveKey(kb.ToggleFuncHelp, "ctrl+x"): func() {
 			if e.candidatemode && len(e.candidates) > 0 &&
 				strings.HasSuffix(e.candidates[0], "(") {
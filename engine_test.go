@@ -2,7 +2,10 @@ package jid
 
 import (
 	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -636,6 +639,240 @@ func TestSetCandidateDataAmpFieldCursorPosition(t *testing.T) {
 	assert.Equal(t, 0, e.placeholderLen)
 }
 
+func TestEngineStatus(t *testing.T) {
+	var assert = assert.New(t)
+
+	e := getEngine(`{"name":"go"}`, "")
+	assert.Equal("", e.status())
+
+	e = getEngine(`{"name":"go"}{"name":"rust"}{"name":"zig"}`, "")
+	assert.Equal("[3 docs]", e.status())
+
+	ee, _ := NewEngine(bytes.NewBufferString(`{"name":"go"}`), &EngineAttribute{Input: InputOption{Format: InputFormatSlurp}})
+	assert.Equal("[1 doc]", ee.(*Engine).status())
+
+	e = getEngine(`{name: 'go', /* comment */}`, "")
+	assert.Equal("[lenient]", e.status())
+}
+
+func TestToggleEmbeddedJSON(t *testing.T) {
+	var assert = assert.New(t)
+
+	e := getEngine(`{"message":"{\"level\":\"warn\"}"}`, ".message.level")
+	assert.Equal([]string{"null"}, e.getContents())
+
+	e.toggleEmbeddedJSON()
+	assert.Equal("[embedded json]", e.status())
+	assert.Equal([]string{`"warn"`}, e.getContents())
+
+	e.toggleEmbeddedJSON()
+	assert.Equal("", e.status())
+	assert.Equal([]string{"null"}, e.getContents())
+}
+
+func TestDecodePreview(t *testing.T) {
+	var assert = assert.New(t)
+
+	e := getEngine(`{"auth":"dXNlcjpzZWNyZXQ=","id":1}`, ".auth")
+	e.togglePreview(previewDecode)
+	assert.Equal("[decoded]", e.status())
+	assert.Equal([]string{"user:secret"}, e.getContents())
+
+	e.query.StringSet(".id")
+	assert.Equal([]string{"(the result is not a string)"}, e.getContents())
+
+	e.togglePreview(previewDecode)
+	assert.Equal("", e.status())
+	assert.Equal([]string{"1"}, e.getContents())
+}
+
+func TestYAMLOutput(t *testing.T) {
+	var assert = assert.New(t)
+
+	// the key order is only recorded for output in a format other than JSON
+	e := getEngine(`{"b":{"z":1,"x":[true]},"a":"s"}`, ".b")
+	e.togglePreview(previewYAML)
+	assert.Equal("[yaml]", e.status())
+	assert.Equal([]string{"x:", "  - true", "z: 1"}, e.getContents())
+
+	cc, err := e.resultContent()
+	assert.Nil(err)
+	assert.Equal(`{"x":[true],"z":1}`, cc)
+
+	ee, err := NewEngine(bytes.NewBufferString(`{"b":{"z":1,"x":[true]},"a":"s"}`), &EngineAttribute{
+		DefaultQuery: ".b",
+		Output:       OutputOption{Format: OutputYAML},
+	})
+	assert.Nil(err)
+	e = ee.(*Engine)
+	e.togglePreview(previewYAML)
+	assert.Equal([]string{"z: 1", "x:", "  - true"}, e.getContents())
+	cc, err = e.resultContent()
+	assert.Nil(err)
+	assert.Equal("z: 1\nx:\n  - true", cc)
+}
+
+func TestTypesPreview(t *testing.T) {
+	var assert = assert.New(t)
+
+	e := getEngine(`{"users":[{"id":1},{"id":2,"name":"b"}]}`, ".users")
+	e.togglePreview(previewTS)
+	assert.Equal("[ts types]", e.status())
+	assert.Equal([]string{"export type Users = User[];", "", "export interface User {", "  id: number;", "  name?: string;", "}"}, e.getContents())
+
+	e.togglePreview(previewGo)
+	assert.Equal("[go types]", e.status())
+	assert.Equal("type Users []User", e.getContents()[0])
+
+	e.togglePreview(previewSchema)
+	assert.Equal("[schema]", e.status())
+	assert.Equal(`  "type": "array",`, e.getContents()[2])
+}
+
+func TestSwitchFile(t *testing.T) {
+	var assert = assert.New(t)
+
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging.json")
+	prod := filepath.Join(dir, "prod.json")
+	_ = os.WriteFile(staging, []byte(`{"replicas":1}`), 0644)
+	_ = os.WriteFile(prod, []byte(`{"replicas":3}`), 0644)
+
+	ee, err := NewEngine(nil, &EngineAttribute{DefaultQuery: ".replicas", Files: []string{staging, prod}})
+	assert.Nil(err)
+	e := ee.(*Engine)
+	assert.Equal("staging.json (1/2)", e.status())
+	assert.Equal([]string{"1"}, e.getContents())
+
+	e.switchFile()
+	assert.Equal(".replicas", e.query.StringGet())
+	assert.Equal("prod.json (2/2)", e.status())
+	assert.Equal([]string{"3"}, e.getContents())
+
+	e.switchFile()
+	assert.Equal("staging.json (1/2)", e.status())
+
+	_, err = NewEngine(nil, &EngineAttribute{Files: []string{staging, filepath.Join(dir, "missing.json")}})
+	assert.NotNil(err)
+}
+
+func TestReloadFiles(t *testing.T) {
+	var assert = assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "job.json")
+	_ = os.WriteFile(path, []byte(`{"state":"running","progress":10}`), 0644)
+
+	_, err := NewEngine(nil, &EngineAttribute{Watch: true})
+	assert.Regexp("watch mode needs files", err.Error())
+
+	ee, err := NewEngine(nil, &EngineAttribute{DefaultQuery: ".progress", Files: []string{path}, Watch: true})
+	assert.Nil(err)
+	e := ee.(*Engine)
+	assert.Equal("job.json [watch]", e.status())
+	assert.Equal([]string{"10"}, e.getContents())
+	e.contentOffset = 3
+
+	_ = os.WriteFile(path, []byte(`{"state":"done","progress":100}`), 0644)
+	e.reloadFiles([]int{0})
+	assert.True(e.reloaded)
+	assert.Equal(".progress", e.query.StringGet())
+	assert.Equal(3, e.contentOffset)
+	assert.Equal([]string{"100"}, e.getContents())
+
+	_ = os.WriteFile(path, []byte(`{"state":`), 0644)
+	e.reloaded = false
+	e.reloadFiles([]int{0})
+	assert.False(e.reloaded)
+	assert.Equal("job.json [reload failed]", e.status())
+	assert.Equal([]string{"100"}, e.getContents())
+}
+
+func TestRerunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	var assert = assert.New(t)
+
+	counter := filepath.Join(t.TempDir(), "count")
+	cmd := `echo x >> ` + counter + `; printf '{"runs":%d}' $(wc -l < ` + counter + `)`
+	ee, err := NewEngine(nil, &EngineAttribute{DefaultQuery: ".runs", Command: cmd})
+	assert.Nil(err)
+	e := ee.(*Engine)
+	assert.Equal([]string{"1"}, e.getContents())
+	assert.Regexp(`^\[ran \d\d:\d\d:\d\d, exit 0\]$`, e.status())
+
+	e.rerunCommand()
+	assert.True(e.reloaded)
+	assert.Equal(".runs", e.query.StringGet())
+	assert.Equal([]string{"2"}, e.getContents())
+
+	e.command = "exit 4"
+	e.rerunCommand()
+	assert.Regexp(`exit 4\] \[reload failed\]$`, e.status())
+	assert.Equal([]string{"2"}, e.getContents())
+
+	_, err = NewEngine(nil, &EngineAttribute{Command: "exit 2"})
+	assert.Regexp("exit status 2", err.Error())
+}
+
+func TestFollowBottom(t *testing.T) {
+	var assert = assert.New(t)
+
+	e := getEngine(`[]`, "")
+	assert.True(e.atBottom(5, 10))
+	e.followBottom(5, 10)
+	assert.Equal(0, e.contentOffset)
+
+	assert.False(e.atBottom(30, 10))
+	e.followBottom(30, 10)
+	assert.Equal(21, e.contentOffset)
+	assert.True(e.atBottom(30, 10))
+	assert.False(e.atBottom(31, 10))
+}
+
+func TestCopy(t *testing.T) {
+	var assert = assert.New(t)
+
+	e := getEngine(`{"users":[{"id":1,"name":"a"}],"total":1}`, ".users[0]")
+	var copied string
+	e.clipboard = func(s string) error {
+		copied = s
+		return nil
+	}
+	e.getContents()
+
+	e.copyResult()
+	assert.Equal(`{"id":1,"name":"a"}`, copied)
+	assert.Equal("[copied result]", e.status())
+
+	e.copyQuery()
+	assert.Equal(".users[0]", copied)
+
+	e.copyPath()
+	assert.Equal(".users[0]", copied)
+	assert.Equal("[copied path .users[0]]", e.status())
+
+	e.query.StringSet(".users[0].na")
+	e.getContents()
+	e.copyPath()
+	assert.Equal(".users[0].name", copied)
+
+	e.query.StringSet(".")
+	e.getContents()
+	e.copyPath()
+	assert.Equal(".", copied)
+
+	e.query.StringSet(".users | length(@)")
+	e.getContents()
+	e.copyPath()
+	assert.Equal("[copy failed: no path for this query]", e.status())
+
+	e.clipboard = func(string) error { return errors.New("no terminal") }
+	e.copyQuery()
+	assert.Equal("[copy failed: no terminal]", e.status())
+}
+
 func getEngine(j string, qs string) *Engine {
 	r := bytes.NewBufferString(j)
 	e, _ := NewEngine(r, &EngineAttribute{
@@ -0,0 +1,356 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// InputFormat selects how the raw input is turned into a JSON document.
+type InputFormat string
+
+const (
+	// InputFormatAuto parses the input as JSON and falls back to the other
+	// formats jid can recognise when that fails.
+	InputFormatAuto InputFormat = ""
+	// InputFormatJSON accepts a single JSON document only.
+	InputFormatJSON InputFormat = "json"
+	// InputFormatLines reads JSON Lines / NDJSON: one JSON value per line.
+	// The records are exposed as a single root array.
+	InputFormatLines InputFormat = "lines"
+	// InputFormatSlurp reads a stream of concatenated JSON values such as
+	// `{...}{...}` and exposes them as a single root array.
+	InputFormatSlurp InputFormat = "slurp"
+	// InputFormatYAML reads YAML, including multi-document `---` streams.
+	InputFormatYAML InputFormat = "yaml"
+	// InputFormatTOML reads a TOML document.
+	InputFormatTOML InputFormat = "toml"
+	// InputFormatCSV reads comma separated values as an array of objects.
+	InputFormatCSV InputFormat = "csv"
+	// InputFormatTSV reads tab separated values as an array of objects.
+	InputFormatTSV InputFormat = "tsv"
+	// InputFormatMsgpack reads a single MessagePack value.
+	InputFormatMsgpack InputFormat = "msgpack"
+	// InputFormatCBOR reads a single CBOR data item.
+	InputFormatCBOR InputFormat = "cbor"
+	// InputFormatXML reads an XML document; see decodeXML for the mapping.
+	InputFormatXML InputFormat = "xml"
+	// InputFormatGron reads gron lines (json.a[0].b = "x";) as written by
+	// the gron output format.
+	InputFormatGron InputFormat = "gron"
+)
+
+// inputFormats lists the formats accepted by ParseInputFormat.
+var inputFormats = []InputFormat{
+	InputFormatJSON,
+	InputFormatLines,
+	InputFormatSlurp,
+	InputFormatYAML,
+	InputFormatTOML,
+	InputFormatCSV,
+	InputFormatTSV,
+	InputFormatMsgpack,
+	InputFormatCBOR,
+	InputFormatXML,
+	InputFormatGron,
+}
+
+// InputOption controls how NewJsonManagerWithOption decodes its input.
+type InputOption struct {
+	Format InputFormat
+	// Delimiter overrides the field separator of CSV/TSV input.
+	// 0 means ',' for CSV and '\t' for TSV.
+	Delimiter rune
+	// NoHeader treats the first CSV/TSV row as data; columns are keyed by index.
+	NoHeader bool
+	// Lenient accepts JSONC / JSON5 input (comments, trailing commas, single
+	// quotes, unquoted keys, NaN / Infinity) for the json and auto formats.
+	// In auto mode the lenient parser is also tried when a strict parse fails.
+	Lenient bool
+	// XMLArrays names XML elements that always become arrays, even when they
+	// occur only once.
+	XMLArrays []string
+	// Lazy indexes a JSON file instead of reading it into memory; subtrees
+	// are parsed only when a query reaches them. The input must be a regular file.
+	Lazy bool
+	// KeyOrder records the key order of every object so that YAML, CSV,
+	// gron, type and template output keep it. JSON output does not need it,
+	// so the extra pass over the input is skipped by default.
+	KeyOrder bool
+	// Progress, when set, is called while a lazy document is being indexed
+	// with the number of bytes scanned so far and the file size.
+	Progress func(done, total int64)
+}
+
+// ParseInputFormat converts a format name given on the command line to an InputFormat.
+func ParseInputFormat(s string) (InputFormat, error) {
+	f := InputFormat(strings.ToLower(strings.TrimSpace(s)))
+	if f == InputFormatAuto || f == "auto" {
+		return InputFormatAuto, nil
+	}
+	if f == "yml" {
+		return InputFormatYAML, nil
+	}
+	for _, known := range inputFormats {
+		if f == known {
+			return f, nil
+		}
+	}
+	return InputFormatAuto, errors.Errorf("unknown input format: %s", s)
+}
+
+// decodedInput is the JSON text produced from the raw input together with
+// what was learned about it while decoding.
+type decodedInput struct {
+	data      []byte
+	format    InputFormat
+	documents int  // number of top-level records; 0 for a single document
+	lenient   bool // the input was normalized by the lenient parser
+}
+
+// decodeInput converts buf into JSON text according to opt.Format.
+// In auto mode a strict JSON parse is tried first so the common case stays cheap.
+func decodeInput(buf []byte, opt *InputOption) (*decodedInput, error) {
+	if opt.Lenient && (opt.Format == InputFormatAuto || opt.Format == InputFormatJSON) && !json.Valid(buf) {
+		data, err := normalizeLenientJSON(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatJSON, lenient: true}, nil
+	}
+
+	switch opt.Format {
+	case InputFormatJSON:
+		return &decodedInput{data: buf, format: InputFormatJSON}, nil
+	case InputFormatLines:
+		data, n, err := decodeJSONLines(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatLines, documents: n}, nil
+	case InputFormatSlurp:
+		data, n, err := decodeJSONStream(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatSlurp, documents: n}, nil
+	case InputFormatYAML:
+		data, n, err := decodeYAML(buf, false)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatYAML, documents: n}, nil
+	case InputFormatTOML:
+		data, err := decodeTOML(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatTOML}, nil
+	case InputFormatCSV, InputFormatTSV:
+		delimiter := opt.Delimiter
+		if delimiter == 0 {
+			delimiter = ','
+			if opt.Format == InputFormatTSV {
+				delimiter = '\t'
+			}
+		}
+		data, err := decodeCSV(buf, delimiter, opt.NoHeader, opt.Format == InputFormatCSV)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: opt.Format}, nil
+	case InputFormatMsgpack:
+		data, err := decodeMsgpack(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatMsgpack}, nil
+	case InputFormatCBOR:
+		data, err := decodeCBOR(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatCBOR}, nil
+	case InputFormatXML:
+		data, err := decodeXML(buf, opt.XMLArrays)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatXML}, nil
+	case InputFormatGron:
+		data, err := decodeGron(buf)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedInput{data: data, format: InputFormatGron}, nil
+	}
+
+	if json.Valid(buf) {
+		return &decodedInput{data: buf, format: InputFormatJSON}, nil
+	}
+	if in := decodeBinary(buf); in != nil {
+		return in, nil
+	}
+	if data, n, err := decodeJSONLines(buf); err == nil && n > 1 {
+		return &decodedInput{data: data, format: InputFormatLines, documents: n}, nil
+	}
+	if data, n, err := decodeJSONStream(buf); err == nil && n > 1 {
+		return &decodedInput{data: data, format: InputFormatSlurp, documents: n}, nil
+	}
+	if data, err := normalizeLenientJSON(buf); err == nil && json.Valid(data) {
+		return &decodedInput{data: data, format: InputFormatJSON, lenient: true}, nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))), []byte("<")) {
+		if data, err := decodeXML(buf, opt.XMLArrays); err == nil {
+			return &decodedInput{data: data, format: InputFormatXML}, nil
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(buf), []byte(gronRoot)) {
+		if data, err := decodeGron(buf); err == nil {
+			return &decodedInput{data: data, format: InputFormatGron}, nil
+		}
+	}
+	if data, n, err := decodeYAML(buf, true); err == nil {
+		return &decodedInput{data: data, format: InputFormatYAML, documents: n}, nil
+	}
+	// Leave the original input in place so the JSON parser reports the error.
+	return &decodedInput{data: buf, format: InputFormatJSON}, nil
+}
+
+// decodeBinary recognises MessagePack and CBOR input from its leading byte.
+// Only a map or array at the top level is accepted, which text input can
+// never start with; the format that decodes the whole input wins.
+func decodeBinary(buf []byte) *decodedInput {
+	if len(buf) == 0 {
+		return nil
+	}
+	c := buf[0]
+	isMsgpack := (0x80 <= c && c <= 0x9f) || (0xdc <= c && c <= 0xdf)
+	isCBOR := (0x80 <= c && c <= 0xbf) || bytes.HasPrefix(buf, []byte{0xd9, 0xd9, 0xf7})
+	if isMsgpack {
+		if data, err := decodeMsgpack(buf); err == nil {
+			return &decodedInput{data: data, format: InputFormatMsgpack}
+		}
+	}
+	if isCBOR {
+		if data, err := decodeCBOR(buf); err == nil {
+			return &decodedInput{data: data, format: InputFormatCBOR}
+		}
+	}
+	return nil
+}
+
+// decodeJSONLines joins every non-blank line of buf into a JSON array.
+// Lines are validated but copied verbatim so numbers keep their precision.
+func decodeJSONLines(buf []byte) ([]byte, int, error) {
+	var out bytes.Buffer
+	out.Grow(len(buf) + 2)
+	out.WriteByte('[')
+	n := 0
+	for i, line := range bytes.Split(buf, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, 0, errors.Errorf("invalid json format at line %d", i+1)
+		}
+		if n > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(line)
+		n++
+	}
+	out.WriteByte(']')
+	return out.Bytes(), n, nil
+}
+
+// decodeJSONStream reads every top-level value from a stream of concatenated
+// JSON documents and joins them into a JSON array.
+func decodeJSONStream(buf []byte) ([]byte, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	var out bytes.Buffer
+	out.Grow(len(buf) + 2)
+	out.WriteByte('[')
+	n := 0
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, errors.Wrapf(err, "invalid json format in document %d", n+1)
+		}
+		if n > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(raw)
+		n++
+	}
+	out.WriteByte(']')
+	return out.Bytes(), n, nil
+}
+
+// binaryPrefix marks byte strings from binary formats, which are shown as
+// base64 text because JSON has no binary type.
+const binaryPrefix = "base64:"
+
+// toJSONValue converts a value produced by one of the non-JSON decoders into
+// the plain map/slice/scalar tree that encoding/json can marshal; objects
+// that keep their key order stay orderedObjects.
+// Map keys become strings, times become RFC 3339 strings, byte strings become
+// base64 text marked with binaryPrefix and non-finite floats, which JSON
+// cannot represent, become null.
+func toJSONValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case *orderedObject:
+		o := newOrderedObject()
+		for _, k := range vv.keys {
+			o.set(k, toJSONValue(vv.values[k]))
+		}
+		return o
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, val := range vv {
+			m[k] = toJSONValue(val)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, val := range vv {
+			m[fmt.Sprint(k)] = toJSONValue(val)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(vv))
+		for i, val := range vv {
+			a[i] = toJSONValue(val)
+		}
+		return a
+	case []map[string]interface{}:
+		a := make([]interface{}, len(vv))
+		for i, val := range vv {
+			a[i] = toJSONValue(val)
+		}
+		return a
+	case time.Time:
+		return formatTime(vv)
+	case []byte:
+		return binaryPrefix + base64.StdEncoding.EncodeToString(vv)
+	case float64:
+		if math.IsNaN(vv) || math.IsInf(vv, 0) {
+			return nil
+		}
+	case float32:
+		if math.IsNaN(float64(vv)) || math.IsInf(float64(vv), 0) {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// {"a":1,"b":[true,nil],"c":bin(01 02),"d":1.5,"n":-128,"m":-1,"t":timestamp32(0),"u":uint16(300)}
+const msgpackData = "\x88" +
+	"\xa1a\x01" +
+	"\xa1b\x92\xc3\xc0" +
+	"\xa1c\xc4\x02\x01\x02" +
+	"\xa1d\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00" +
+	"\xa1n\xd0\x80" +
+	"\xa1m\xff" +
+	"\xa1t\xd6\xff\x00\x00\x00\x00" +
+	"\xa1u\xcd\x01\x2c"
+
+func TestDecodeMsgpack(t *testing.T) {
+	var assert = assert.New(t)
+
+	data, err := decodeMsgpack([]byte(msgpackData))
+	assert.Nil(err)
+	assert.Equal(`{"a":1,"b":[true,null],"c":"base64:AQI=","d":1.5,"n":-128,"m":-1,"t":"1970-01-01T00:00:00Z","u":300}`, string(data))
+
+	// array16 with an integer key map inside
+	data, err = decodeMsgpack([]byte("\xdc\x00\x02\x81\x01\xa1x\xd9\x03abc"))
+	assert.Nil(err)
+	assert.Equal(`[{"1":"x"},"abc"]`, string(data))
+
+	_, err = decodeMsgpack([]byte("\x82\xa1a\x01"))
+	assert.Regexp("invalid msgpack format: unexpected end of data", err.Error())
+
+	_, err = decodeMsgpack([]byte("\x80\x01"))
+	assert.Regexp("invalid msgpack format: unexpected data", err.Error())
+
+	// a length larger than the input is rejected before allocating
+	_, err = decodeMsgpack([]byte("\xdd\xff\xff\xff\xff"))
+	assert.NotNil(err)
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	var assert = assert.New(t)
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"\xa2\x61a\x01\x61b\x82\x02\x03", `{"a":1,"b":[2,3]}`},
+		{"\xbf\x61a\x01\x61b\x9f\x02\x03\xff\xff", `{"a":1,"b":[2,3]}`},
+		{"\x82\x44\x01\x02\x03\x04\x7f\x65strea\x64ming\xff", `["base64:AQIDBA==","streaming"]`},
+		{"\x81\xc1\x1a\x51\x4b\x67\xb0", `["2013-03-21T20:04:00Z"]`},
+		{"\x81\xc2\x49\x01\x00\x00\x00\x00\x00\x00\x00\x00", `[18446744073709551616]`},
+		{"\x85\xf9\x3e\x00\xf9\xc4\x00\x20\x39\x03\xe7\xfb\x7f\xf8\x00\x00\x00\x00\x00\x00", `[1.5,-4,-1,-1000,null]`},
+		{"\x83\xf5\xf4\xf6", `[true,false,null]`},
+		{"\xd9\xd9\xf7\xa1\x01\x61x", `{"1":"x"}`},
+	}
+	for _, c := range cases {
+		data, err := decodeCBOR([]byte(c.in))
+		assert.Nil(err, c.want)
+		assert.Equal(c.want, string(data))
+	}
+
+	_, err := decodeCBOR([]byte("\x82\x01"))
+	assert.Regexp("invalid cbor format: unexpected end of data", err.Error())
+
+	_, err = decodeCBOR([]byte("\x9f\x01"))
+	assert.NotNil(err)
+
+	_, err = decodeCBOR([]byte("\xff"))
+	assert.Regexp("unexpected break", err.Error())
+}
+
+func TestNewJsonManagerWithBinaryInput(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(msgpackData), &InputOption{Format: InputFormatMsgpack})
+	assert.Nil(err)
+	assert.Equal(InputFormatMsgpack, jm.Format())
+	result, _, _, _ := jm.Get(NewQueryWithString(".c"), true)
+	assert.Equal(`"base64:AQI="`, result)
+
+	// detected from the leading byte
+	jm, err = NewJsonManager(bytes.NewBufferString(msgpackData))
+	assert.Nil(err)
+	assert.Equal(InputFormatMsgpack, jm.Format())
+
+	jm, err = NewJsonManager(bytes.NewBufferString("\xa2\x61a\x01\x61b\x82\x02\x03"))
+	assert.Nil(err)
+	assert.Equal(InputFormatCBOR, jm.Format())
+	result, _, _, _ = jm.Get(NewQueryWithString(".b[1]"), true)
+	assert.Equal(`3`, result)
+
+	// 0x82 0x01 0x02 is a valid CBOR array but a truncated MessagePack map
+	jm, err = NewJsonManager(bytes.NewBufferString("\x82\x01\x02"))
+	assert.Nil(err)
+	assert.Equal(InputFormatCBOR, jm.Format())
+
+	jm, err = NewJsonManagerWithOption(bytes.NewBufferString(`{"a":1}`), &InputOption{Format: InputFormatCBOR})
+	assert.Nil(jm)
+	assert.Regexp("invalid cbor format", err.Error())
+}
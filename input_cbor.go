@@ -0,0 +1,285 @@
+package jid
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cborBreak is returned by cborDecoder.value for the "break" stop code that
+// ends an indefinite-length item.
+var cborBreak = &struct{}{}
+
+// decodeCBOR converts a CBOR (RFC 8949) data item into JSON text.
+func decodeCBOR(buf []byte) ([]byte, error) {
+	d := &cborDecoder{buf: buf}
+	v, err := d.value(0)
+	if err == nil && v == cborBreak {
+		err = errors.New("unexpected break")
+	}
+	if err == nil && d.pos != len(buf) {
+		err = errors.Errorf("unexpected data at offset %d", d.pos)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cbor format")
+	}
+	data, err := json.Marshal(toJSONValue(v))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cbor format")
+	}
+	return data, nil
+}
+
+type cborDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, errors.Errorf("unexpected end of data at offset %d", d.pos)
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// argument reads the argument that follows an initial byte with additional
+// information info. indefinite is true for info 31.
+func (d *cborDecoder) argument(info byte) (n uint64, indefinite bool, err error) {
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info == 31:
+		return 0, true, nil
+	case info > 27:
+		return 0, false, errors.Errorf("invalid additional information %d at offset %d", info, d.pos-1)
+	}
+	b, err := d.next(1 << (info - 24))
+	if err != nil {
+		return 0, false, err
+	}
+	switch len(b) {
+	case 1:
+		return uint64(b[0]), false, nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), false, nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), false, nil
+	}
+	return binary.BigEndian.Uint64(b), false, nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("nesting too deep")
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+
+	if major == 7 {
+		return d.simple(info)
+	}
+	n, indefinite, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major < 2 || major == 6) {
+		return nil, errors.Errorf("invalid indefinite length at offset %d", d.pos-1)
+	}
+
+	switch major {
+	case 0:
+		return n, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(n)), nil
+		}
+		return -1 - int64(n), nil
+	case 2, 3:
+		var raw []byte
+		if indefinite {
+			if raw, err = d.chunks(major, depth); err != nil {
+				return nil, err
+			}
+		} else if raw, err = d.next(n); err != nil {
+			return nil, err
+		}
+		if major == 2 {
+			return raw, nil
+		}
+		return string(raw), nil
+	case 4:
+		a := []interface{}{}
+		for i := uint64(0); indefinite || i < n; i++ {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if v == cborBreak {
+				if !indefinite {
+					return nil, errors.New("unexpected break")
+				}
+				break
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	case 5:
+		m := newOrderedObject()
+		for i := uint64(0); indefinite || i < n; i++ {
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if k == cborBreak {
+				if !indefinite {
+					return nil, errors.New("unexpected break")
+				}
+				break
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if v == cborBreak {
+				return nil, errors.New("unexpected break")
+			}
+			m.set(fmt.Sprint(k), v)
+		}
+		return m, nil
+	}
+	// major 6: tagged item
+	v, err := d.value(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if v == cborBreak {
+		return nil, errors.New("unexpected break")
+	}
+	return cborTagged(n, v), nil
+}
+
+// chunks concatenates the definite-length chunks of an indefinite-length
+// byte or text string.
+func (d *cborDecoder) chunks(major byte, depth int) ([]byte, error) {
+	var out []byte
+	for {
+		if d.pos < len(d.buf) && d.buf[d.pos] == 0xff {
+			d.pos++
+			return out, nil
+		}
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0]>>5 != major {
+			return nil, errors.Errorf("invalid chunk at offset %d", d.pos-1)
+		}
+		n, indefinite, err := d.argument(b[0] & 0x1f)
+		if err != nil {
+			return nil, err
+		}
+		if indefinite {
+			return nil, errors.Errorf("nested indefinite chunk at offset %d", d.pos-1)
+		}
+		chunk, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+func (d *cborDecoder) simple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.next(2)
+		if err != nil {
+			return nil, err
+		}
+		return float16ToFloat64(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 31:
+		return cborBreak, nil
+	case 24:
+		if _, err := d.next(1); err != nil {
+			return nil, err
+		}
+	}
+	// unassigned simple values carry no JSON meaning
+	return nil, nil
+}
+
+// cborTagged applies the standard tags jid can represent: epoch times and
+// bignums. Other tags are dropped and their content kept.
+func cborTagged(tag uint64, v interface{}) interface{} {
+	switch tag {
+	case 1:
+		switch t := v.(type) {
+		case uint64:
+			return time.Unix(int64(t), 0).UTC()
+		case int64:
+			return time.Unix(t, 0).UTC()
+		case float64:
+			sec, frac := math.Modf(t)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		}
+	case 2, 3:
+		if raw, ok := v.([]byte); ok {
+			n := new(big.Int).SetBytes(raw)
+			if tag == 3 {
+				n.Sub(big.NewInt(-1), n)
+			}
+			return n
+		}
+	}
+	return v
+}
+
+func float16ToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,91 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// reJSONNumber matches cell text that is already a valid JSON number.
+// Values such as "007" or "1." are left as strings.
+var reJSONNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// decodeCSV converts delimited text into a JSON array with one object per row.
+// Keys come from the header row, or are the column indexes ("0", "1", ...)
+// when noHeader is set. Cells are typed by csvCellValue.
+// CSV follows RFC 4180 quoting; TSV has no quoting, so every line is split
+// on the delimiter as is.
+func decodeCSV(buf []byte, delimiter rune, noHeader bool, quoted bool) ([]byte, error) {
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	var records [][]string
+	if quoted {
+		r := csv.NewReader(bytes.NewReader(buf))
+		r.Comma = delimiter
+		r.FieldsPerRecord = -1
+		var err error
+		if records, err = r.ReadAll(); err != nil {
+			return nil, errors.Wrap(err, "invalid csv format")
+		}
+	} else {
+		for _, line := range strings.Split(string(buf), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if line == "" {
+				continue
+			}
+			records = append(records, strings.Split(line, string(delimiter)))
+		}
+	}
+
+	var header []string
+	rows := []interface{}{}
+	for _, record := range records {
+		if header == nil && !noHeader {
+			header = make([]string, len(record))
+			for i, h := range record {
+				header[i] = strings.TrimSpace(h)
+			}
+			continue
+		}
+		// keys follow the columns; cells past the header are keyed by index
+		row := newOrderedObject()
+		for i := range header {
+			row.set(header[i], nil)
+		}
+		for i, cell := range record {
+			key := strconv.Itoa(i)
+			if i < len(header) {
+				key = header[i]
+			}
+			row.set(key, csvCellValue(cell))
+		}
+		rows = append(rows, row)
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid csv format")
+	}
+	return data, nil
+}
+
+// csvCellValue infers the JSON type of a cell: empty cells are null,
+// true/false are booleans, JSON-compatible numbers are numbers and anything
+// else is a string.
+func csvCellValue(cell string) interface{} {
+	s := strings.TrimSpace(cell)
+	switch {
+	case s == "":
+		return nil
+	case strings.EqualFold(s, "true"):
+		return true
+	case strings.EqualFold(s, "false"):
+		return false
+	case reJSONNumber.MatchString(s):
+		return json.Number(s)
+	}
+	return cell
+}
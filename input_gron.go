@@ -0,0 +1,223 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// gronValue is a node of a document rebuilt from gron lines. Object keys keep
+// the order they were first assigned in.
+type gronValue struct {
+	keys   []string
+	object map[string]*gronValue
+	array  []*gronValue
+	raw    json.RawMessage
+}
+
+func (g *gronValue) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	switch {
+	case g == nil:
+		return []byte("null"), nil
+	case g.object != nil:
+		buf.WriteByte('{')
+		for i, k := range g.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(jsonQuote(k))
+			buf.WriteByte(':')
+			b, err := g.object[k].MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		buf.WriteByte('}')
+	case g.array != nil:
+		buf.WriteByte('[')
+		for i, e := range g.array {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			b, err := e.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		buf.WriteByte(']')
+	case g.raw != nil:
+		return g.raw, nil
+	default:
+		return []byte("null"), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// maxGronPadding is how many array elements a gron document may have beyond
+// one per line. Lines left by grep can skip indexes, which are filled with
+// null, but a single line must not make an array of millions of elements.
+const maxGronPadding = 1 << 16
+
+// decodeGron rebuilds a JSON document from gron lines such as
+// json.users[0].name = "x"; as written by --output gron. Keys may also be
+// written as ["key"], as gron itself does. Containers that have no line of
+// their own (e.g. after grep) are created from the paths below them.
+func decodeGron(buf []byte) ([]byte, error) {
+	var root *gronValue
+	lines := strings.Split(string(buf), "\n")
+	slots := len(lines) + maxGronPadding
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		path, value, err := parseGronLine(line)
+		if err == nil {
+			err = gronSet(&root, path, 0, value, &slots)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid gron format at line %d", i+1)
+		}
+	}
+	if root == nil {
+		return nil, errors.New("invalid gron format: no lines")
+	}
+	return root.MarshalJSON()
+}
+
+// gronPath is the sequence of keys (string) and indexes (int) of a line.
+type gronPath []interface{}
+
+func (p gronPath) String() string {
+	var sb strings.Builder
+	sb.WriteString(gronRoot)
+	for _, e := range p {
+		switch t := e.(type) {
+		case string:
+			sb.WriteString(gronKey(t))
+		case int:
+			sb.WriteString("[" + strconv.Itoa(t) + "]")
+		}
+	}
+	return sb.String()
+}
+
+// gronSet assigns value at path[i:] below *slot, creating the containers on
+// the way. Assigning {} or [] to a container of the same kind keeps its
+// contents, so the order of the lines does not matter. slots is the number
+// of array elements that may still be added.
+func gronSet(slot **gronValue, path gronPath, i int, value *gronValue, slots *int) error {
+	if i == len(path) {
+		old := *slot
+		if old == nil || !(old.object != nil && value.object != nil || old.array != nil && value.array != nil) {
+			*slot = value
+		}
+		return nil
+	}
+	switch t := path[i].(type) {
+	case string:
+		if *slot == nil {
+			*slot = &gronValue{object: map[string]*gronValue{}}
+		}
+		g := *slot
+		if g.object == nil {
+			return errors.Errorf("%s is not an object", path[:i])
+		}
+		child, ok := g.object[t]
+		if !ok {
+			g.keys = append(g.keys, t)
+		}
+		err := gronSet(&child, path, i+1, value, slots)
+		g.object[t] = child
+		return err
+	case int:
+		if *slot == nil {
+			*slot = &gronValue{array: []*gronValue{}}
+		}
+		g := *slot
+		if g.array == nil {
+			return errors.Errorf("%s is not an array", path[:i])
+		}
+		if n := t + 1 - len(g.array); n > 0 {
+			if n > *slots {
+				return errors.Errorf("index %d of %s is too far past the end of the array", t, path[:i])
+			}
+			*slots -= n
+			g.array = append(g.array, make([]*gronValue, n)...)
+		}
+		return gronSet(&g.array[t], path, i+1, value, slots)
+	}
+	return nil
+}
+
+// parseGronLine splits a line into its path below json and its value.
+func parseGronLine(line string) (gronPath, *gronValue, error) {
+	if !strings.HasPrefix(line, gronRoot) {
+		return nil, nil, errors.Errorf("line does not start with %s", gronRoot)
+	}
+	s := line[len(gronRoot):]
+	var path gronPath
+	for !strings.HasPrefix(strings.TrimLeft(s, " "), "=") {
+		switch {
+		case s == "":
+			return nil, nil, errors.New("missing =")
+		case strings.HasPrefix(s, `.\"`):
+			end := strings.Index(s[3:], `\"`)
+			if end < 0 {
+				return nil, nil, errors.New(`unterminated \"`)
+			}
+			path = append(path, s[3:3+end])
+			s = s[3+end+2:]
+		case strings.HasPrefix(s, `["`):
+			var key string
+			d := json.NewDecoder(strings.NewReader(s[1:]))
+			if err := d.Decode(&key); err != nil {
+				return nil, nil, errors.Wrap(err, "invalid key")
+			}
+			rest := strings.TrimLeft(s[1+int(d.InputOffset()):], " ")
+			if !strings.HasPrefix(rest, "]") {
+				return nil, nil, errors.New("missing ]")
+			}
+			path = append(path, key)
+			s = rest[1:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, nil, errors.New("missing ]")
+			}
+			n, err := strconv.Atoi(s[1:end])
+			if err != nil || n < 0 {
+				return nil, nil, errors.New("invalid index")
+			}
+			path = append(path, n)
+			s = s[end+1:]
+		case s[0] == '.':
+			end := strings.IndexAny(s[1:], ".[=")
+			if end < 0 {
+				return nil, nil, errors.New("missing =")
+			}
+			path = append(path, strings.TrimRight(s[1:1+end], " "))
+			s = s[1+end:]
+		default:
+			return nil, nil, errors.Errorf("unexpected %q", s)
+		}
+	}
+	s = strings.TrimSpace(strings.TrimSpace(s)[1:])
+	s = strings.TrimSpace(strings.TrimSuffix(s, ";"))
+	switch s {
+	case "{}":
+		return path, &gronValue{object: map[string]*gronValue{}}, nil
+	case "[]":
+		return path, &gronValue{array: []*gronValue{}}, nil
+	}
+	if !json.Valid([]byte(s)) || s[0] == '{' || s[0] == '[' {
+		return nil, nil, errors.Errorf("invalid value %s", s)
+	}
+	return path, &gronValue{raw: json.RawMessage(s)}, nil
+}
@@ -0,0 +1,90 @@
+package jid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeGron(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := `json = {};
+json.users = [];
+json.users[0] = {};
+json.users[0].name = "Ann";
+json.users[0].tags = [];
+json.users[1] = {};
+json.users[1].name = "Bob";
+json.users[1].\"e.mail\" = "bob@example.com";
+json.count = 2;
+`
+	out, err := decodeGron([]byte(data))
+	assert.Nil(err)
+	assert.Equal(`{"users":[{"name":"Ann","tags":[]},{"name":"Bob","e.mail":"bob@example.com"}],"count":2}`, string(out))
+
+	// lines left by grep still rebuild their parents; gron's ["key"] is read too
+	out, err = decodeGron([]byte("json.users[1].name = \"Bob\";\njson[\"a b\"].id = 1.50;"))
+	assert.Nil(err)
+	assert.Equal(`{"users":[null,{"name":"Bob"}],"a b":{"id":1.50}}`, string(out))
+
+	// a container assignment does not drop what was set before it
+	out, err = decodeGron([]byte("json.a.b = true;\njson.a = {};"))
+	assert.Nil(err)
+	assert.Equal(`{"a":{"b":true}}`, string(out))
+}
+
+func TestGronRoundTrip(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := `{"line\nbreak":{"tab\tand\u0001":1},"say \\\"hi\\\"":[{"a.b":"x","":null,"ok_1":true}],"[0]":"y"}`
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+	s, err := jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputGron}, true)
+	assert.Nil(err)
+	// every assignment stays on one line
+	assert.Equal(9, len(strings.Split(s, "\n")))
+	assert.Contains(s, `json["line\nbreak"]["tab\tand\u0001"] = 1;`)
+	assert.Contains(s, `json["say \\\"hi\\\""][0].ok_1 = true;`)
+
+	out, err := decodeGron([]byte(s))
+	assert.Nil(err)
+	assert.Equal(data, string(out))
+}
+
+func TestDecodeGronError(t *testing.T) {
+	var assert = assert.New(t)
+
+	_, err := decodeGron([]byte("json.a = 1;\njson.a.b = 2;"))
+	assert.Equal("invalid gron format at line 2: json.a is not an object", err.Error())
+
+	_, err = decodeGron([]byte("json.a = {\"b\": 1};"))
+	assert.Regexp("invalid value", err.Error())
+
+	_, err = decodeGron([]byte("data.a = 1;"))
+	assert.Regexp("line 1: line does not start with json", err.Error())
+
+	_, err = decodeGron([]byte("json[20000000] = 1;"))
+	assert.Equal("invalid gron format at line 1: index 20000000 of json is too far past the end of the array", err.Error())
+
+	_, err = decodeGron([]byte("json.a[60000] = 1;\njson.b[60000] = 1;"))
+	assert.Regexp("line 2: index 60000 of json.b is too far", err.Error())
+
+	// the limit also applies when gron input is detected automatically
+	_, err = NewJsonManager(bytes.NewBufferString("json[20000000] = 1;"))
+	assert.NotNil(err)
+}
+
+func TestNewJsonManagerWithGron(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString("json = {};\njson.b = 1;\njson.a = \"x\";\n"), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+	assert.Equal(InputFormatGron, jm.Format())
+
+	s, err := jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputGron}, true)
+	assert.Nil(err)
+	assert.Equal("json = {};\njson.b = 1;\njson.a = \"x\";", s)
+}
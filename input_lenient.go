@@ -0,0 +1,240 @@
+package jid
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// normalizeLenientJSON rewrites JSONC / JSON5 style input into standard JSON.
+// It removes // and /* */ comments and trailing commas, turns single-quoted
+// strings and unquoted keys into double-quoted ones, converts hexadecimal and
+// '+'-prefixed numbers, and replaces NaN / Infinity (which JSON cannot
+// represent) with null. The result still has to pass a strict parse.
+func normalizeLenientJSON(buf []byte) ([]byte, error) {
+	l := &lenientScanner{src: bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))}
+	if err := l.run(); err != nil {
+		return nil, errors.Wrap(err, "invalid json format")
+	}
+	return l.out.Bytes(), nil
+}
+
+type lenientScanner struct {
+	src []byte
+	pos int
+	out bytes.Buffer
+}
+
+func (l *lenientScanner) run() error {
+	l.out.Grow(len(l.src))
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '/':
+			if err := l.skipComment(); err != nil {
+				return err
+			}
+		case c == '"' || c == '\'':
+			if err := l.string(c); err != nil {
+				return err
+			}
+		case c == ',':
+			l.pos++
+			if next := l.peekSignificant(); next != ']' && next != '}' {
+				l.out.WriteByte(',')
+			}
+		case c == '+' || c == '-' || c == '.' || ('0' <= c && c <= '9'):
+			if err := l.number(); err != nil {
+				return err
+			}
+		case isIdentStart(c):
+			if err := l.identifier(); err != nil {
+				return err
+			}
+		default:
+			l.out.WriteByte(c)
+			l.pos++
+		}
+	}
+	return nil
+}
+
+// skipComment skips a comment starting at l.pos. A comment is replaced by a
+// single space so tokens on either side stay separated.
+func (l *lenientScanner) skipComment() error {
+	if l.pos+1 >= len(l.src) {
+		return errors.Errorf("unexpected '/' at offset %d", l.pos)
+	}
+	switch l.src[l.pos+1] {
+	case '/':
+		end := bytes.IndexByte(l.src[l.pos:], '\n')
+		if end < 0 {
+			l.pos = len(l.src)
+		} else {
+			l.pos += end
+		}
+	case '*':
+		end := bytes.Index(l.src[l.pos+2:], []byte("*/"))
+		if end < 0 {
+			return errors.Errorf("unterminated comment at offset %d", l.pos)
+		}
+		l.pos += end + 4
+		l.out.WriteByte(' ')
+	default:
+		return errors.Errorf("unexpected '/' at offset %d", l.pos)
+	}
+	return nil
+}
+
+// peekSignificant returns the next byte that is neither whitespace nor part
+// of a comment, without consuming anything. Returns 0 at end of input.
+func (l *lenientScanner) peekSignificant() byte {
+	for i := l.pos; i < len(l.src); i++ {
+		switch c := l.src[i]; c {
+		case ' ', '\t', '\r', '\n':
+		case '/':
+			if i+1 < len(l.src) && l.src[i+1] == '/' {
+				end := bytes.IndexByte(l.src[i:], '\n')
+				if end < 0 {
+					return 0
+				}
+				i += end
+			} else if i+1 < len(l.src) && l.src[i+1] == '*' {
+				end := bytes.Index(l.src[i+2:], []byte("*/"))
+				if end < 0 {
+					return 0
+				}
+				i += end + 3
+			} else {
+				return c
+			}
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// string copies a string literal delimited by quote as a double-quoted JSON string.
+func (l *lenientScanner) string(quote byte) error {
+	start := l.pos
+	l.pos++
+	l.out.WriteByte('"')
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			l.out.WriteByte('"')
+			return nil
+		case c == '\\' && l.pos+1 < len(l.src):
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '\'':
+				l.out.WriteByte('\'')
+			case '\n':
+				// JSON5 line continuation
+			case '\r':
+				if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+					l.pos++
+				}
+			case 'x':
+				if l.pos+2 > len(l.src) {
+					return errors.Errorf("invalid escape at offset %d", l.pos-2)
+				}
+				l.out.WriteString(`\u00`)
+				l.out.Write(l.src[l.pos : l.pos+2])
+				l.pos += 2
+			default:
+				l.out.WriteByte('\\')
+				l.out.WriteByte(esc)
+			}
+		case c == '"':
+			l.out.WriteString(`\"`)
+			l.pos++
+		case c == '\n':
+			return errors.Errorf("unterminated string at offset %d", start)
+		default:
+			l.out.WriteByte(c)
+			l.pos++
+		}
+	}
+	return errors.Errorf("unterminated string at offset %d", start)
+}
+
+// number copies a numeric literal, converting the JSON5-only forms.
+func (l *lenientScanner) number() error {
+	start := l.pos
+	sign := ""
+	if c := l.src[l.pos]; c == '+' || c == '-' {
+		if c == '-' {
+			sign = "-"
+		}
+		l.pos++
+	}
+	end := l.pos
+	for end < len(l.src) && (isIdentPart(l.src[end]) || l.src[end] == '.' ||
+		((l.src[end] == '+' || l.src[end] == '-') && (l.src[end-1] == 'e' || l.src[end-1] == 'E'))) {
+		end++
+	}
+	lit := string(l.src[l.pos:end])
+	l.pos = end
+
+	switch {
+	case lit == "Infinity" || lit == "NaN":
+		l.out.WriteString("null")
+		return nil
+	case strings.HasPrefix(lit, "0x") || strings.HasPrefix(lit, "0X"):
+		n, err := strconv.ParseUint(lit[2:], 16, 64)
+		if err != nil {
+			return errors.Errorf("invalid number at offset %d", start)
+		}
+		l.out.WriteString(sign + strconv.FormatUint(n, 10))
+		return nil
+	}
+	if strings.HasPrefix(lit, ".") {
+		lit = "0" + lit
+	}
+	if strings.HasSuffix(lit, ".") {
+		lit = strings.TrimSuffix(lit, ".")
+	}
+	lit = strings.Replace(lit, ".e", "e", 1)
+	lit = strings.Replace(lit, ".E", "E", 1)
+	l.out.WriteString(sign + lit)
+	return nil
+}
+
+// identifier copies true/false/null, maps NaN/Infinity to null and quotes
+// bare object keys.
+func (l *lenientScanner) identifier() error {
+	start := l.pos
+	for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+		l.pos++
+	}
+	ident := string(l.src[start:l.pos])
+	if l.peekSignificant() == ':' {
+		l.out.WriteString(strconv.Quote(ident))
+		return nil
+	}
+	switch ident {
+	case "true", "false", "null":
+		l.out.WriteString(ident)
+	case "NaN", "Infinity":
+		l.out.WriteString("null")
+	default:
+		return errors.Errorf("unexpected identifier %q at offset %d", ident, start)
+	}
+	return nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= utf8.RuneSelf
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || ('0' <= c && c <= '9')
+}
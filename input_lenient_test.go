@@ -0,0 +1,82 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLenientJSON(t *testing.T) {
+	var assert = assert.New(t)
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`{"a": 1, // comment
+  "b": [1, 2, ], /* block
+  comment */ }`, `{"a": 1, 
+  "b": [1, 2 ]   }`},
+		{`{'a': 'it\'s "q"'}`, `{"a": "it's \"q\""}`},
+		{`{unquoted_key: $x, $y: true}`, ``},
+		{`{a: NaN, b: -Infinity, c: +Infinity, d: Infinity}`, `{"a": null, "b": null, "c": null, "d": null}`},
+		{`[0x1F, -0xA, +1, .5, 5., 1.e3, 1e-3]`, `[31, -10, 1, 0.5, 5, 1e3, 1e-3]`},
+		{`{"url": "http://example.com/*x*/"}`, `{"url": "http://example.com/*x*/"}`},
+		{`'line \
+continued \x41'`, `"line continued \u0041"`},
+	}
+	for _, c := range cases {
+		out, err := normalizeLenientJSON([]byte(c.in))
+		if c.want == "" {
+			assert.NotNil(err, c.in)
+			continue
+		}
+		assert.Nil(err, c.in)
+		assert.Equal(c.want, string(out), c.in)
+	}
+}
+
+func TestNormalizeLenientJSONError(t *testing.T) {
+	var assert = assert.New(t)
+
+	_, err := normalizeLenientJSON([]byte(`{"a": 1 /* open`))
+	assert.Regexp("unterminated comment", err.Error())
+
+	_, err = normalizeLenientJSON([]byte(`{'a: 1}`))
+	assert.Regexp("unterminated string", err.Error())
+}
+
+func TestNewJsonManagerWithLenient(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := `// tsconfig.json
+{
+  compilerOptions: {
+    target: 'es2020',
+    strict: true, // keep
+  },
+  "exclude": ["node_modules",],
+}`
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Lenient: true})
+	assert.Nil(err)
+	assert.True(jm.Lenient())
+	result, _, _, err := jm.Get(NewQueryWithString(".compilerOptions"), true)
+	assert.Nil(err)
+	assert.Equal(`{"strict":true,"target":"es2020"}`, result)
+
+	// automatic fallback after a strict parse error
+	jm, err = NewJsonManager(bytes.NewBufferString(data))
+	assert.Nil(err)
+	assert.True(jm.Lenient())
+	assert.Equal(InputFormatJSON, jm.Format())
+
+	// strict input does not switch to the lenient path
+	jm, err = NewJsonManagerWithOption(bytes.NewBufferString(`{"a":1}`), &InputOption{Lenient: true})
+	assert.Nil(err)
+	assert.False(jm.Lenient())
+
+	jm, err = NewJsonManagerWithOption(bytes.NewBufferString(`{a: }`), &InputOption{Lenient: true})
+	assert.Nil(jm)
+	assert.Regexp("invalid json format", err.Error())
+}
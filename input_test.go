@@ -0,0 +1,284 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInputFormat(t *testing.T) {
+	var assert = assert.New(t)
+
+	f, err := ParseInputFormat("LINES")
+	assert.Nil(err)
+	assert.Equal(InputFormatLines, f)
+
+	f, err = ParseInputFormat("")
+	assert.Nil(err)
+	assert.Equal(InputFormatAuto, f)
+
+	f, err = ParseInputFormat("yml")
+	assert.Nil(err)
+	assert.Equal(InputFormatYAML, f)
+
+	_, err = ParseInputFormat("ini")
+	assert.Regexp("unknown input format", err.Error())
+}
+
+func TestNewJsonManagerWithLines(t *testing.T) {
+	var assert = assert.New(t)
+
+	r := bytes.NewBufferString("{\"level\":\"info\",\"id\":1}\n\n{\"level\":\"warn\",\"id\":12345678901234567890}\r\n")
+	jm, err := NewJsonManagerWithOption(r, &InputOption{Format: InputFormatLines})
+	assert.Nil(err)
+	assert.Equal(InputFormatLines, jm.Format())
+	assert.Equal(2, jm.Documents())
+
+	result, _, _, err := jm.Get(NewQueryWithString(".[1]"), true)
+	assert.Nil(err)
+	assert.Equal(`{"id":12345678901234567890,"level":"warn"}`, result)
+
+	// JMESPath works against the records array unchanged
+	result, _, _, err = jm.Get(NewQueryWithString(".[*].level"), true)
+	assert.Nil(err)
+	assert.Equal(`["info","warn"]`, result)
+
+	// a single line is still an array in lines mode
+	jm, err = NewJsonManagerWithOption(bytes.NewBufferString(`{"a":1}`), &InputOption{Format: InputFormatLines})
+	assert.Nil(err)
+	assert.Equal(1, jm.Documents())
+	result, _, _, _ = jm.Get(NewQueryWithString("."), true)
+	assert.Equal(`[{"a":1}]`, result)
+}
+
+func TestNewJsonManagerWithLinesError(t *testing.T) {
+	var assert = assert.New(t)
+
+	r := bytes.NewBufferString("{\"a\":1}\n{\"a\":\n")
+	jm, err := NewJsonManagerWithOption(r, &InputOption{Format: InputFormatLines})
+	assert.Nil(jm)
+	assert.Regexp("invalid json format at line 2", err.Error())
+}
+
+func TestNewJsonManagerDetectsLines(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManager(bytes.NewBufferString("{\"a\":1}\n{\"a\":2}\n"))
+	assert.Nil(err)
+	assert.Equal(InputFormatLines, jm.Format())
+	assert.Equal(2, jm.Documents())
+
+	// a pretty-printed document stays a single JSON document
+	jm, err = NewJsonManager(bytes.NewBufferString("{\n  \"a\": 1\n}\n"))
+	assert.Nil(err)
+	assert.Equal(InputFormatJSON, jm.Format())
+	assert.Equal(0, jm.Documents())
+}
+
+func TestNewJsonManagerWithSlurp(t *testing.T) {
+	var assert = assert.New(t)
+
+	r := bytes.NewBufferString(`{"kind":"Pod"}{"kind":"Service"}  [1,2]` + "\n\"done\"")
+	jm, err := NewJsonManagerWithOption(r, &InputOption{Format: InputFormatSlurp})
+	assert.Nil(err)
+	assert.Equal(InputFormatSlurp, jm.Format())
+	assert.Equal(4, jm.Documents())
+
+	result, _, _, err := jm.Get(NewQueryWithString("."), true)
+	assert.Nil(err)
+	assert.Equal(`[{"kind":"Pod"},{"kind":"Service"},[1,2],"done"]`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(".[1].kind"), true)
+	assert.Nil(err)
+	assert.Equal(`"Service"`, result)
+}
+
+func TestNewJsonManagerWithSlurpError(t *testing.T) {
+	var assert = assert.New(t)
+
+	r := bytes.NewBufferString(`{"a":1}{"a":`)
+	jm, err := NewJsonManagerWithOption(r, &InputOption{Format: InputFormatSlurp})
+	assert.Nil(jm)
+	assert.Regexp("invalid json format in document 2", err.Error())
+}
+
+func TestNewJsonManagerDetectsSlurp(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManager(bytes.NewBufferString("{\n  \"a\": 1\n}\n{\n  \"a\": 2\n}\n"))
+	assert.Nil(err)
+	assert.Equal(InputFormatSlurp, jm.Format())
+	assert.Equal(2, jm.Documents())
+}
+
+func TestNewJsonManagerWithYAML(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  created: 2024-01-02T03:04:05Z
+  date: 2002-12-14
+  spaced: 2001-12-14 21:59:43.10 -5
+data:
+  1: one
+  enabled: true
+  ratio: .5
+  items: [a, b]
+`
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatYAML})
+	assert.Nil(err)
+	assert.Equal(InputFormatYAML, jm.Format())
+	assert.Equal(0, jm.Documents())
+
+	result, _, _, err := jm.Get(NewQueryWithString(".data"), true)
+	assert.Nil(err)
+	assert.Equal(`{"1":"one","enabled":true,"items":["a","b"],"ratio":0.5}`, result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".metadata.created"), true)
+	assert.Equal(`"2024-01-02T03:04:05Z"`, result)
+
+	// timestamps keep their source text
+	result, _, _, _ = jm.Get(NewQueryWithString(".metadata.date"), true)
+	assert.Equal(`"2002-12-14"`, result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".metadata.spaced"), true)
+	assert.Equal(`"2001-12-14 21:59:43.10 -5"`, result)
+}
+
+func TestNewJsonManagerWithYAMLMultiDocument(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := "---\nkind: Service\n---\nkind: Deployment\n---\n"
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatYAML})
+	assert.Nil(err)
+	assert.Equal(2, jm.Documents())
+
+	result, _, _, err := jm.Get(NewQueryWithString(".[*].kind"), true)
+	assert.Nil(err)
+	assert.Equal(`["Service","Deployment"]`, result)
+}
+
+func TestNewJsonManagerWithYAMLError(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString("a: [1, 2\n"), &InputOption{Format: InputFormatYAML})
+	assert.Nil(jm)
+	assert.Regexp("invalid yaml format", err.Error())
+}
+
+func TestNewJsonManagerDetectsYAML(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManager(bytes.NewBufferString("name: go\ntags:\n  - fast\n"))
+	assert.Nil(err)
+	assert.Equal(InputFormatYAML, jm.Format())
+	result, _, _, _ := jm.Get(NewQueryWithString(".tags[0]"), true)
+	assert.Equal(`"fast"`, result)
+
+	// plain text is not taken for a YAML scalar
+	jm, err = NewJsonManager(bytes.NewBufferString("hello world"))
+	assert.Nil(jm)
+	assert.Regexp("invalid json format", err.Error())
+}
+
+func TestNewJsonManagerWithTOML(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := `
+[package]
+name = "jid"
+edition = 2021
+released = 1979-05-27T07:32:00Z
+local = 1979-05-27T07:32:00
+date = 1979-05-27
+time = 07:32:00
+
+[[bin]]
+name = "a"
+built = 2024-01-02T03:04:05+09:00
+
+[[bin]]
+name = "b"
+`
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatTOML})
+	assert.Nil(err)
+	assert.Equal(InputFormatTOML, jm.Format())
+
+	result, _, _, err := jm.Get(NewQueryWithString(".package"), true)
+	assert.Nil(err)
+	assert.Equal(`{"date":"1979-05-27","edition":2021,"local":"1979-05-27T07:32:00","name":"jid","released":"1979-05-27T07:32:00Z","time":"07:32:00"}`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(".bin[*].name"), true)
+	assert.Nil(err)
+	assert.Equal(`["a","b"]`, result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".bin[0].built"), true)
+	assert.Equal(`"2024-01-02T03:04:05+09:00"`, result)
+}
+
+func TestNewJsonManagerWithTOMLError(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString("a = "), &InputOption{Format: InputFormatTOML})
+	assert.Nil(jm)
+	assert.Regexp("invalid toml format", err.Error())
+}
+
+func TestNewJsonManagerWithCSV(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := "\xef\xbb\xbfname,price,stock,zip,note\n" +
+		"apple,1.5,true,007,\"red, sweet\"\n" +
+		"pear,12,FALSE,,\n" +
+		"plum,-3e2,false,1000,x,extra\n"
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatCSV})
+	assert.Nil(err)
+	assert.Equal(InputFormatCSV, jm.Format())
+
+	result, _, _, err := jm.Get(NewQueryWithString(".[0]"), true)
+	assert.Nil(err)
+	assert.Equal(`{"name":"apple","note":"red, sweet","price":1.5,"stock":true,"zip":"007"}`, result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".[1]"), true)
+	assert.Equal(`{"name":"pear","note":null,"price":12,"stock":false,"zip":null}`, result)
+
+	result, _, _, _ = jm.Get(NewQueryWithString(".[2]"), true)
+	assert.Equal(`{"5":"extra","name":"plum","note":"x","price":-3e2,"stock":false,"zip":1000}`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(".[?price > `5`].name"), true)
+	assert.Nil(err)
+	assert.Equal(`["pear"]`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(". | sort_by(@, &price)[*].name"), true)
+	assert.Nil(err)
+	assert.Equal(`["plum","apple","pear"]`, result)
+}
+
+func TestNewJsonManagerWithTSVNoHeader(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := "a\t1\n\"b\t2\n"
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatTSV, NoHeader: true})
+	assert.Nil(err)
+
+	result, _, _, err := jm.Get(NewQueryWithString("."), true)
+	assert.Nil(err)
+	assert.Equal(`[{"0":"a","1":1},{"0":"\"b","1":2}]`, result)
+
+	data = "id;name\n1;x\n"
+	jm, err = NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatCSV, Delimiter: ';'})
+	assert.Nil(err)
+	result, _, _, _ = jm.Get(NewQueryWithString("."), true)
+	assert.Equal(`[{"id":1,"name":"x"}]`, result)
+}
+
+func TestNewJsonManagerWithCSVError(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString("a,b\n\"x,1\n"), &InputOption{Format: InputFormatCSV})
+	assert.Nil(jm)
+	assert.Regexp("invalid csv format", err.Error())
+}
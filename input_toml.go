@@ -0,0 +1,115 @@
+package jid
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+// tomlLocalLayouts maps the zone names BurntSushi/toml gives to local
+// date-times, dates and times (values written without an offset) to the
+// RFC 3339 layout that keeps them without an offset.
+var tomlLocalLayouts = map[string]string{
+	"datetime-local": "2006-01-02T15:04:05.999999999",
+	"date-local":     "2006-01-02",
+	"time-local":     "15:04:05.999999999",
+}
+
+// decodeTOML converts a TOML document into JSON text.
+func decodeTOML(buf []byte) ([]byte, error) {
+	var m map[string]interface{}
+	md, err := toml.Decode(string(buf), &m)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid toml format")
+	}
+	data, err := json.Marshal(tomlOrdered(m, md.Keys()))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid toml format")
+	}
+	return data, nil
+}
+
+// tomlOrdered converts m like toJSONValue but writes the keys of each table
+// in the order of keys, the keys of the document in source order. A key
+// naming an array of tables starts its next element.
+func tomlOrdered(m map[string]interface{}, keys []toml.Key) *orderedObject {
+	root := newOrderedObject()
+	for _, key := range keys {
+		o, t := root, m
+		for i, name := range key {
+			switch v := t[name].(type) {
+			case []map[string]interface{}:
+				a, _ := o.values[name].([]interface{})
+				if i == len(key)-1 {
+					if len(a) < len(v) {
+						o.set(name, append(a, newOrderedObject()))
+					}
+					break
+				}
+				if len(a) == 0 {
+					break
+				}
+				o, t = a[len(a)-1].(*orderedObject), v[len(a)-1]
+				continue
+			case map[string]interface{}:
+				c, ok := o.values[name].(*orderedObject)
+				if !ok {
+					c = newOrderedObject()
+					o.set(name, c)
+				}
+				o, t = c, v
+				continue
+			default:
+				if i == len(key)-1 {
+					o.set(name, toJSONValue(v))
+				}
+			}
+			break
+		}
+	}
+	tomlFill(root, m)
+	return root
+}
+
+// tomlFill adds the values of m that tomlOrdered did not reach to o, in
+// sorted order.
+func tomlFill(o *orderedObject, m map[string]interface{}) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case map[string]interface{}:
+			if c, ok := o.values[k].(*orderedObject); ok {
+				tomlFill(c, v)
+				continue
+			}
+		case []map[string]interface{}:
+			if a, ok := o.values[k].([]interface{}); ok && len(a) == len(v) {
+				for i, e := range a {
+					tomlFill(e.(*orderedObject), v[i])
+				}
+				continue
+			}
+		default:
+			if _, ok := o.values[k]; ok {
+				continue
+			}
+		}
+		o.set(k, toJSONValue(m[k]))
+	}
+}
+
+// formatTime renders t as an RFC 3339 string. Local TOML date-times, dates
+// and times have no offset in the source, so none is added.
+func formatTime(t time.Time) string {
+	if layout, ok := tomlLocalLayouts[t.Location().String()]; ok {
+		return t.Format(layout)
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,145 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// decodeXML converts an XML document into JSON text using these rules:
+//
+//   - the root element becomes the only key of the root object
+//   - attributes become "@name" keys and text content becomes "#text"
+//   - an element with neither attributes nor child elements becomes its
+//     text as a string, or null when it is empty
+//   - child elements that repeat become arrays; names in forceArrays are
+//     arrays even when they occur once, so paths stay stable
+//   - namespace prefixes are dropped and xmlns declarations are ignored
+//
+// All values are strings; XML carries no type information.
+func decodeXML(buf []byte, forceArrays []string) ([]byte, error) {
+	force := map[string]bool{}
+	for _, name := range forceArrays {
+		force[name] = true
+	}
+	dec := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))))
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = xmlCharsetReader
+
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "invalid xml format")
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+					continue
+				}
+				n.attrs = append(n.attrs, a)
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root != nil {
+				return nil, errors.New("invalid xml format: multiple root elements")
+			} else {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("invalid xml format: unexpected end element")
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("invalid xml format: no root element")
+	}
+	if len(stack) > 0 {
+		return nil, errors.New("invalid xml format: unclosed element")
+	}
+
+	var v interface{} = root.value(force)
+	if force[root.name] {
+		v = []interface{}{v}
+	}
+	data, err := json.Marshal(map[string]interface{}{root.name: v})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid xml format")
+	}
+	return data, nil
+}
+
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     bytes.Buffer
+}
+
+func (n *xmlNode) value(force map[string]bool) interface{} {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+
+	// keys follow the attributes and the first occurrence of each child
+	m := newOrderedObject()
+	for _, a := range n.attrs {
+		m.set("@"+a.Name.Local, a.Value)
+	}
+	if text != "" {
+		m.set("#text", text)
+	}
+	counts := map[string]int{}
+	for _, c := range n.children {
+		counts[c.name]++
+	}
+	for _, c := range n.children {
+		v := c.value(force)
+		if counts[c.name] > 1 || force[c.name] {
+			a, _ := m.values[c.name].([]interface{})
+			m.set(c.name, append(a, v))
+		} else {
+			m.set(c.name, v)
+		}
+	}
+	return m
+}
+
+// xmlCharsetReader supports the single-byte Latin-1 declarations that older
+// feeds use; other encodings are rejected.
+func xmlCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "us-ascii":
+		raw, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, errors.Errorf("unsupported charset: %s", charset)
+}
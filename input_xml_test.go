@@ -0,0 +1,66 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeXML(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>News</title>
+    <item id="1"><title>First</title><dc:creator>a</dc:creator></item>
+    <item id="2"><title>Second</title><empty/></item>
+    <note lang="en">hello <![CDATA[<world>]]></note>
+  </channel>
+</rss>`
+	out, err := decodeXML([]byte(data), nil)
+	assert.Nil(err)
+	assert.Equal(`{"rss":{"@version":"2.0","channel":{"title":"News","item":[{"@id":"1","title":"First","creator":"a"},{"@id":"2","title":"Second","empty":null}],"note":{"@lang":"en","#text":"hello \u003cworld\u003e"}}}}`, string(out))
+
+	// a single element is only an array when forced
+	data = `<project><dependencies><dependency><artifactId>x</artifactId></dependency></dependencies></project>`
+	out, err = decodeXML([]byte(data), nil)
+	assert.Nil(err)
+	assert.Equal(`{"project":{"dependencies":{"dependency":{"artifactId":"x"}}}}`, string(out))
+
+	out, err = decodeXML([]byte(data), []string{"dependency"})
+	assert.Nil(err)
+	assert.Equal(`{"project":{"dependencies":{"dependency":[{"artifactId":"x"}]}}}`, string(out))
+}
+
+func TestDecodeXMLError(t *testing.T) {
+	var assert = assert.New(t)
+
+	_, err := decodeXML([]byte(`<a><b></a>`), nil)
+	assert.Regexp("invalid xml format", err.Error())
+
+	_, err = decodeXML([]byte(`<a/><b/>`), nil)
+	assert.Regexp("multiple root elements", err.Error())
+
+	_, err = decodeXML([]byte(`just text`), nil)
+	assert.Regexp("no root element", err.Error())
+}
+
+func TestNewJsonManagerWithXML(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := "\xef\xbb\xbf<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<a><b>caf\xe9</b><b>2</b></a>"
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: InputFormatXML})
+	assert.Nil(err)
+	assert.Equal(InputFormatXML, jm.Format())
+	result, _, _, _ := jm.Get(NewQueryWithString(".a.b[0]"), true)
+	assert.Equal(`"café"`, result)
+
+	// detected automatically
+	jm, err = NewJsonManager(bytes.NewBufferString(`  <a x="1"/>`))
+	assert.Nil(err)
+	assert.Equal(InputFormatXML, jm.Format())
+	result, _, _, _ = jm.Get(NewQueryWithString(".a"), true)
+	assert.Equal(`{"@x":"1"}`, result)
+}
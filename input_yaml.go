@@ -0,0 +1,187 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML converts a YAML stream into JSON text. A single document becomes
+// the root value; several `---` separated documents become a root array and
+// their count is returned. Empty documents are skipped.
+// When collectionOnly is set, a document whose root is a plain scalar is
+// rejected; auto-detection uses this because almost any text is valid YAML.
+func decodeYAML(buf []byte, collectionOnly bool) ([]byte, int, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(buf))
+	docs := []interface{}{}
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, errors.Wrap(err, "invalid yaml format")
+		}
+		keepTimestamps(&node)
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return nil, 0, errors.Wrap(err, "invalid yaml format")
+		}
+		if v == nil {
+			continue
+		}
+		if collectionOnly {
+			switch v.(type) {
+			case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			default:
+				return nil, 0, errors.New("invalid yaml format: not a mapping or sequence")
+			}
+		}
+		docs = append(docs, yamlOrdered(v, &node))
+	}
+
+	var root interface{}
+	n := 0
+	switch len(docs) {
+	case 0:
+		return nil, 0, errors.New("invalid yaml format: no documents")
+	case 1:
+		root = docs[0]
+	default:
+		root = docs
+		n = len(docs)
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "invalid yaml format")
+	}
+	return data, n, nil
+}
+
+// keepTimestamps retags timestamp scalars as strings so they keep their
+// source text. yaml.v3 only parses some timestamp forms and would turn a
+// date into an invented midnight UTC.
+func keepTimestamps(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!timestamp" {
+		n.Tag = "!!str"
+	}
+	for _, c := range n.Content {
+		keepTimestamps(c)
+	}
+}
+
+// yamlOrdered converts v, the decoding of n, like toJSONValue but writes the
+// keys of each mapping in the order of the source, following aliases and
+// merge keys (<<) as the decoder does.
+func yamlOrdered(v interface{}, n *yaml.Node) interface{} {
+	for n != nil && (n.Kind == yaml.DocumentNode || n.Kind == yaml.AliasNode) {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		} else if len(n.Content) > 0 {
+			n = n.Content[0]
+		} else {
+			n = nil
+		}
+	}
+	var m map[string]interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m = t
+	case map[interface{}]interface{}:
+		m = make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = e
+		}
+	case []interface{}:
+		if n == nil || n.Kind != yaml.SequenceNode || len(n.Content) != len(t) {
+			return toJSONValue(v)
+		}
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = yamlOrdered(e, n.Content[i])
+		}
+		return a
+	default:
+		return toJSONValue(v)
+	}
+
+	o := newOrderedObject()
+	for _, p := range yamlPairs(n) {
+		if e, ok := m[p.key]; ok {
+			o.set(p.key, yamlOrdered(e, p.value))
+		}
+	}
+	// keys the walk did not find, if any, follow in sorted order
+	var rest []string
+	for k := range m {
+		if _, ok := o.values[k]; !ok {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		o.set(k, toJSONValue(m[k]))
+	}
+	return o
+}
+
+type yamlPair struct {
+	key   string
+	value *yaml.Node
+}
+
+// yamlPairs returns the keys of the mapping n and their value nodes in
+// source order. The keys of merged mappings come in place of their << key;
+// as in the decoder, an explicit key wins over a merged one and an earlier
+// merged mapping over a later one.
+func yamlPairs(n *yaml.Node) []yamlPair {
+	if n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	explicit := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := n.Content[i]; k.ShortTag() != "!!merge" {
+			explicit[yamlKey(k)] = true
+		}
+	}
+	var pairs []yamlPair
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.ShortTag() != "!!merge" {
+			key := yamlKey(k)
+			seen[key] = true
+			pairs = append(pairs, yamlPair{key: key, value: v})
+			continue
+		}
+		merged := []*yaml.Node{v}
+		if v.Kind == yaml.SequenceNode {
+			merged = v.Content
+		}
+		for _, m := range merged {
+			for _, p := range yamlPairs(m) {
+				if !explicit[p.key] && !seen[p.key] {
+					seen[p.key] = true
+					pairs = append(pairs, p)
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// yamlKey returns the JSON key of a mapping key node, as toJSONValue writes it.
+func yamlKey(k *yaml.Node) string {
+	var v interface{}
+	if err := k.Decode(&v); err != nil {
+		return k.Value
+	}
+	return fmt.Sprint(v)
+}
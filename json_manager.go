@@ -2,6 +2,8 @@ package jid
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -20,23 +22,61 @@ type JsonManager struct {
 	origin     *simplejson.Json
 	originData interface{}
 	suggestion *Suggestion
+	format     InputFormat
+	documents  int
+	lenient    bool
+	name       string
+	lazy       *lazyDocument
+	// embedded JSON view (SetExpandEmbedded)
+	expanded bool
+	embedded *JsonManager
+	raw      *simplejson.Json
+	rawData  interface{}
+	// key order of the objects in the document, for non-JSON output; nil
+	// unless InputOption.KeyOrder was set
+	order keyOrder
 }
 
 func NewJsonManager(reader io.Reader) (*JsonManager, error) {
+	return NewJsonManagerWithOption(reader, &InputOption{})
+}
+
+// NewJsonManagerWithOption reads the whole input and decodes it as described by opt.
+// With opt.Lazy the input must be a regular file, which is indexed instead of read.
+func NewJsonManagerWithOption(reader io.Reader, opt *InputOption) (*JsonManager, error) {
+	if opt.Lazy {
+		return newLazyJsonManager(reader, opt)
+	}
+
 	buf, err := io.ReadAll(reader)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid data")
 	}
 
-	j, err2 := simplejson.NewJson(buf)
+	if buf, err = decompress(buf); err != nil {
+		return nil, err
+	}
+
+	in, err := decodeInput(buf, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJsonManager(in, opt.KeyOrder)
+}
+
+// newJsonManager builds a manager over already decoded JSON text. With
+// keepOrder the key order of its objects is recorded for non-JSON output.
+func newJsonManager(in *decodedInput, keepOrder bool) (*JsonManager, error) {
+	j, err2 := simplejson.NewJson(in.data)
 
 	if err2 != nil {
 		return nil, errors.Wrap(err2, "invalid json format")
 	}
 
 	var originData interface{}
-	if err3 := json.Unmarshal(buf, &originData); err3 != nil {
+	if err3 := json.Unmarshal(in.data, &originData); err3 != nil {
 		return nil, errors.Wrap(err3, "invalid json format")
 	}
 
@@ -45,11 +85,70 @@ func NewJsonManager(reader io.Reader) (*JsonManager, error) {
 		current:    j,
 		originData: originData,
 		suggestion: NewSuggestion(),
+		format:     in.format,
+		documents:  in.documents,
+		lenient:    in.lenient,
+	}
+	if keepOrder {
+		jm.order = scanKeyOrder(in.data, j.Interface(), originData)
 	}
 
 	return jm, nil
 }
 
+// NewJsonManagerFromFile loads the document at path. Errors are prefixed with
+// the path and the file name is kept for display.
+func NewJsonManagerFromFile(path string, opt *InputOption) (*JsonManager, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	jm, err := NewJsonManagerWithOption(f, opt)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, path)
+	}
+	if jm.lazy == nil {
+		f.Close()
+	}
+	jm.name = filepath.Base(path)
+	return jm, nil
+}
+
+// Close releases the file held open by a lazily loaded document.
+func (jm *JsonManager) Close() error {
+	if jm.lazy != nil {
+		return jm.lazy.Close()
+	}
+	return nil
+}
+
+// Lazy reports whether the document is indexed and materialized on demand.
+func (jm *JsonManager) Lazy() bool {
+	return jm.lazy != nil
+}
+
+// Name returns the file name the document was loaded from, or "" for stdin.
+func (jm *JsonManager) Name() string {
+	return jm.name
+}
+
+// Format returns the input format the document was decoded from.
+func (jm *JsonManager) Format() InputFormat {
+	return jm.format
+}
+
+// Lenient reports whether the input only parsed after JSONC / JSON5 normalization.
+func (jm *JsonManager) Lenient() bool {
+	return jm.lenient
+}
+
+// Documents returns the number of top-level records read from a multi-record
+// input such as JSON Lines, or 0 for a single document.
+func (jm *JsonManager) Documents() int {
+	return jm.documents
+}
+
 func (jm *JsonManager) Get(q QueryInterface, confirm bool) (string, []string, []string, error) {
 	j, suggestion, candidates, _ := jm.GetFilteredData(q, confirm)
 
@@ -122,17 +221,16 @@ func jmespathExprFromQuery(qs string) string {
 }
 
 // evalJMESPath evaluates a JMESPath expression against the raw JSON data and
-// returns the result as a *simplejson.Json.
+// returns the result as a *simplejson.Json. The result shares its objects
+// with the document, so they keep their key order.
 func (jm *JsonManager) evalJMESPath(expr string) (*simplejson.Json, error) {
 	result, err := jmespath.Search(expr, jm.originData)
 	if err != nil {
 		return nil, err
 	}
-	b, err := json.Marshal(result)
-	if err != nil {
-		return nil, err
-	}
-	return simplejson.NewJson(b)
+	j := simplejson.New()
+	j.SetPath(nil, result)
+	return j, nil
 }
 
 // evalBaseExpr evaluates expr like evalJMESPath but transparently rewrites
@@ -207,8 +305,16 @@ func pipeSuffix(qs string) string {
 
 
 func (jm *JsonManager) GetFilteredData(q QueryInterface, confirm bool) (*simplejson.Json, []string, []string, error) {
+	if jm.lazy != nil {
+		return jm.lazy.getFilteredData(q, confirm)
+	}
+
 	qs := q.StringGet()
 
+	if loc := reFromJSON.FindStringIndex(qs); loc != nil {
+		return jm.getFilteredDataFromJSON(qs, loc, confirm)
+	}
+
 	if isJMESPathQuery(qs) {
 		return jm.getFilteredDataJMESPath(qs, confirm)
 	}
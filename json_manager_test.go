@@ -3,6 +3,8 @@ package jid
 import (
 	"bytes"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	simplejson "github.com/bitly/go-simplejson"
@@ -24,6 +26,7 @@ func TestNewJson(t *testing.T) {
 		origin:     sj,
 		originData: map[string]interface{}{"name": "go"},
 		suggestion: NewSuggestion(),
+		format:     InputFormatJSON,
 	})
 	assert.Nil(e)
 
@@ -40,6 +43,28 @@ func TestNewJsonWithError(t *testing.T) {
 	assert.Regexp("invalid json format", e.Error())
 }
 
+func TestNewJsonManagerFromFile(t *testing.T) {
+	var assert = assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "staging.json")
+	assert.Nil(os.WriteFile(path, []byte(`{"name":"go"}`), 0644))
+
+	jm, err := NewJsonManagerFromFile(path, &InputOption{})
+	assert.Nil(err)
+	assert.Equal("staging.json", jm.Name())
+	assert.Equal("go", jm.origin.Get("name").MustString())
+
+	broken := filepath.Join(dir, "broken.json")
+	assert.Nil(os.WriteFile(broken, []byte(`{"name":`), 0644))
+	jm, err = NewJsonManagerFromFile(broken, &InputOption{Format: InputFormatJSON})
+	assert.Nil(jm)
+	assert.Regexp("broken.json: invalid json format", err.Error())
+
+	_, err = NewJsonManagerFromFile(filepath.Join(dir, "missing.json"), &InputOption{})
+	assert.NotNil(err)
+}
+
 func TestGet(t *testing.T) {
 	var assert = assert.New(t)
 
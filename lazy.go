@@ -0,0 +1,649 @@
+package jid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	simplejson "github.com/bitly/go-simplejson"
+	"github.com/pkg/errors"
+)
+
+var (
+	// lazyMaterializeLimit is the largest subtree parsed in full when a query
+	// reaches it. Larger containers are shown as a skeleton.
+	lazyMaterializeLimit int64 = 32 << 20
+	// lazyInlineLimit is the largest child value a skeleton shows in full.
+	lazyInlineLimit int64 = 64 << 10
+	// lazySkeletonBudget caps the bytes of child values parsed into one skeleton.
+	lazySkeletonBudget int64 = 8 << 20
+	// lazyPreviewItems caps the number of elements a skeleton array lists.
+	lazyPreviewItems = 1000
+	// lazyCacheSize is the number of materialized subtrees kept in memory.
+	lazyCacheSize = 8
+)
+
+const (
+	lazyReadBuffer   = 1 << 20
+	lazyProgressStep = 4 << 20
+)
+
+// lazyNode is a JSON value inside a lazily loaded file, identified by its byte
+// range. The direct children of a container are indexed on first use.
+type lazyNode struct {
+	start, end int64
+	kind       byte // '{', '[' or 0 for scalars
+
+	indexed  bool
+	starts   []int64
+	ends     []int64
+	kinds    []byte
+	keys     []string
+	keyIndex map[string]int
+	children map[int]*lazyNode
+}
+
+type lazyEntry struct {
+	node *lazyNode
+	jm   *JsonManager
+}
+
+// lazyDocument is the index over a JSON file loaded in lazy mode.
+type lazyDocument struct {
+	file     *os.File
+	size     int64
+	root     *lazyNode
+	cache    []*lazyEntry
+	progress func(done, total int64)
+	expand   bool // decode embedded JSON strings (SetExpandEmbedded)
+	order    bool // record key order (InputOption.KeyOrder)
+}
+
+// lazyStep is one plain path segment (.key, .\"key\" or [N]) of a query.
+type lazyStep struct {
+	key   string
+	index int // -1 for object keys
+	end   int // offset in the query just past this segment
+}
+
+func newLazyJsonManager(reader io.Reader, opt *InputOption) (*JsonManager, error) {
+	if opt.Format != InputFormatAuto && opt.Format != InputFormatJSON {
+		return nil, errors.Errorf("lazy loading supports json input only, not %s", opt.Format)
+	}
+	f, ok := reader.(*os.File)
+	if !ok {
+		return nil, errors.New("lazy loading needs a file")
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid data")
+	}
+	if !st.Mode().IsRegular() {
+		return nil, errors.New("lazy loading needs a regular file, not a pipe")
+	}
+
+	d := &lazyDocument{file: f, size: st.Size(), progress: opt.Progress, order: opt.KeyOrder}
+	if err := d.index(); err != nil {
+		return nil, err
+	}
+
+	empty := simplejson.New()
+	return &JsonManager{
+		origin:     empty,
+		current:    empty,
+		suggestion: NewSuggestion(),
+		format:     InputFormatJSON,
+		lazy:       d,
+	}, nil
+}
+
+// Close closes the underlying file.
+func (d *lazyDocument) Close() error {
+	d.cache = nil
+	return d.file.Close()
+}
+
+// index locates the top-level value and indexes its direct children.
+func (d *lazyDocument) index() error {
+	head := make([]byte, 4)
+	if n, _ := d.file.ReadAt(head, 0); n >= 2 && (isGzip(head[:n]) || isBzip2(head[:n])) {
+		return errors.New("lazy loading does not support compressed input")
+	}
+
+	r := bufio.NewReaderSize(io.NewSectionReader(d.file, 0, d.size), lazyReadBuffer)
+	start := int64(0)
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			return errors.New("invalid json format: empty input")
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid data")
+		}
+		if !isJSONSpace(c) {
+			d.root = &lazyNode{start: start, end: d.size}
+			if c == '{' || c == '[' {
+				d.root.kind = c
+			}
+			break
+		}
+		start++
+	}
+
+	if d.root.kind == 0 {
+		if d.progress != nil {
+			d.progress(d.size, d.size)
+			d.progress = nil
+		}
+		return nil
+	}
+	if err := d.scan(d.root); err != nil {
+		return err
+	}
+
+	r = bufio.NewReaderSize(io.NewSectionReader(d.file, d.root.end, d.size-d.root.end), lazyReadBuffer)
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid data")
+		}
+		if !isJSONSpace(c) {
+			return errors.New("invalid json format: unexpected data after the top-level value")
+		}
+	}
+	if d.progress != nil {
+		d.progress(d.size, d.size)
+		d.progress = nil
+	}
+	return nil
+}
+
+// lazyScanLevels is the number of nesting levels indexed in one pass, so that
+// descending into a big child of the scanned node needs no second pass.
+const lazyScanLevels = 2
+
+// lazyFrame is a container whose direct children are being recorded by scan.
+type lazyFrame struct {
+	node       *lazyNode
+	childStart int64
+}
+
+// beginChild notes that a direct child of f starting with c begins at pos.
+// f is nil for values nested too deep to be indexed.
+func (f *lazyFrame) beginChild(pos int64, c byte) {
+	if f == nil || f.childStart >= 0 {
+		return
+	}
+	f.childStart = pos
+	if c != '{' && c != '[' {
+		c = 0
+	}
+	f.node.kinds = append(f.node.kinds, c)
+}
+
+// scan records the byte range of every direct child of the container n (and
+// the keys of an object) and sets n.end just past its closing bracket. The
+// children of containers nested up to lazyScanLevels deep are indexed in the
+// same pass. Deeper values are only checked for balanced brackets; they are
+// validated when materialized.
+func (d *lazyDocument) scan(n *lazyNode) error {
+	buf := make([]byte, lazyReadBuffer)
+	off := n.start
+	nextReport := n.start + lazyProgressStep
+	var frames []*lazyFrame
+	depth := 0
+	inString, escaped, readingKey := false, false, false
+	last := int64(-1)
+	var key []byte
+
+	finishChild := func(f *lazyFrame, pos int64) error {
+		if f.childStart < 0 {
+			return errors.Errorf("invalid json format at offset %d", pos)
+		}
+		n := f.node
+		n.starts = append(n.starts, f.childStart)
+		n.ends = append(n.ends, last+1)
+		if n.kind == '{' && len(n.keys) != len(n.starts) {
+			return errors.Errorf("invalid json format at offset %d", f.childStart)
+		}
+		f.childStart = -1
+		return nil
+	}
+
+	for {
+		size, err := d.file.ReadAt(buf, off)
+		if size == 0 {
+			if err == nil || err == io.EOF {
+				return errors.New("invalid json format: unexpected end of input")
+			}
+			return errors.Wrap(err, "invalid data")
+		}
+		chunk := buf[:size]
+
+		for i := 0; i < len(chunk); i++ {
+			c := chunk[i]
+			pos := off + int64(i)
+
+			if inString {
+				if !readingKey && !escaped {
+					// skip to the next quote or escape
+					j := bytes.IndexAny(chunk[i:], "\"\\")
+					if j < 0 {
+						i = len(chunk) - 1
+						last = off + int64(i)
+						continue
+					}
+					i += j
+					c = chunk[i]
+					pos = off + int64(i)
+				}
+				if readingKey {
+					key = append(key, c)
+				}
+				switch {
+				case escaped:
+					escaped = false
+				case c == '\\':
+					escaped = true
+				case c == '"':
+					inString = false
+					if readingKey {
+						readingKey = false
+						name, err := decodeJSONKey(key)
+						if err != nil {
+							return errors.Wrapf(err, "invalid json format at offset %d", pos)
+						}
+						f := frames[len(frames)-1]
+						f.node.keys = append(f.node.keys, name)
+					}
+				}
+				last = pos
+				continue
+			}
+
+			var top *lazyFrame
+			if depth > 0 && len(frames) == depth {
+				top = frames[depth-1]
+			}
+			switch c {
+			case ' ', '\t', '\n', '\r':
+				continue
+			case '"':
+				inString = true
+				if top != nil && top.node.kind == '{' && top.childStart < 0 && len(top.node.keys) == len(top.node.starts) {
+					readingKey = true
+					key = append(key[:0], c)
+				} else {
+					top.beginChild(pos, c)
+				}
+			case '{', '[':
+				if depth == 0 {
+					frames = append(frames, &lazyFrame{node: n, childStart: -1})
+					depth = 1
+					continue
+				}
+				top.beginChild(pos, c)
+				if top != nil && depth < lazyScanLevels {
+					child := &lazyNode{start: pos, end: n.end, kind: c}
+					if top.node.children == nil {
+						top.node.children = map[int]*lazyNode{}
+					}
+					top.node.children[len(top.node.starts)] = child
+					frames = append(frames, &lazyFrame{node: child, childStart: -1})
+				}
+				depth++
+			case '}', ']':
+				if top != nil {
+					if top.childStart >= 0 {
+						if err := finishChild(top, pos); err != nil {
+							return err
+						}
+					} else if len(top.node.starts) > 0 || len(top.node.keys) > 0 {
+						return errors.Errorf("invalid json format at offset %d", pos)
+					}
+					top.node.end = pos + 1
+					top.node.indexed = true
+					frames = frames[:len(frames)-1]
+				}
+				depth--
+				if depth == 0 {
+					if d.progress != nil {
+						d.progress(pos, d.size)
+					}
+					return nil
+				}
+			case ':':
+				if top != nil && top.node.kind == '{' {
+					continue
+				}
+			case ',':
+				if top != nil {
+					if err := finishChild(top, pos); err != nil {
+						return err
+					}
+					continue
+				}
+			default:
+				top.beginChild(pos, c)
+			}
+			last = pos
+		}
+
+		off += int64(size)
+		if d.progress != nil && off >= nextReport {
+			d.progress(off, d.size)
+			nextReport += lazyProgressStep
+		}
+	}
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func decodeJSONKey(raw []byte) (string, error) {
+	if !strings.Contains(string(raw), `\`) {
+		return string(raw[1 : len(raw)-1]), nil
+	}
+	var s string
+	err := json.Unmarshal(raw, &s)
+	return s, err
+}
+
+// child returns the i-th direct child of the indexed node n.
+func (d *lazyDocument) child(n *lazyNode, i int) *lazyNode {
+	if c, ok := n.children[i]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = map[int]*lazyNode{}
+	}
+	c := &lazyNode{start: n.starts[i], end: n.ends[i], kind: n.kinds[i]}
+	n.children[i] = c
+	return c
+}
+
+// lookup returns the child of n reached by step, or nil when there is none.
+func (d *lazyDocument) lookup(n *lazyNode, step lazyStep) (*lazyNode, error) {
+	if n.kind == 0 {
+		return nil, nil
+	}
+	if !n.indexed {
+		if err := d.scan(n); err != nil {
+			return nil, err
+		}
+	}
+	if step.index >= 0 {
+		if n.kind != '[' || step.index >= len(n.starts) {
+			return nil, nil
+		}
+		return d.child(n, step.index), nil
+	}
+	if n.kind != '{' {
+		return nil, nil
+	}
+	if n.keyIndex == nil {
+		n.keyIndex = make(map[string]int, len(n.keys))
+		for i, k := range n.keys {
+			n.keyIndex[k] = i
+		}
+	}
+	i, ok := n.keyIndex[step.key]
+	if !ok {
+		return nil, nil
+	}
+	return d.child(n, i), nil
+}
+
+// ambiguous reports whether keys other than key in the object n start with it,
+// in which case the query is still being completed at n.
+func (d *lazyDocument) ambiguous(n *lazyNode, key string) bool {
+	prefix := strings.ToLower(key)
+	for _, k := range n.keys {
+		if k != key && strings.HasPrefix(strings.ToLower(k), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve walks the plain path at the start of qs through the index and
+// returns the deepest node reached and the offset in qs where the rest of the
+// query starts. The last segment is only followed when it cannot be the
+// beginning of another key, so completion keeps working at its parent.
+func (d *lazyDocument) resolve(qs string, confirm bool) (*lazyNode, int, error) {
+	n, consumed := d.root, 0
+	for _, step := range lazyPathPrefix(qs) {
+		c, err := d.lookup(n, step)
+		if err != nil {
+			return nil, 0, err
+		}
+		if c == nil {
+			break
+		}
+		if step.end == len(qs) && step.index < 0 && !confirm && d.ambiguous(n, step.key) {
+			break
+		}
+		n, consumed = c, step.end
+	}
+	return n, consumed, nil
+}
+
+// lazyPathPrefix parses the leading plain path of a query.
+func lazyPathPrefix(qs string) []lazyStep {
+	var steps []lazyStep
+	i := 0
+	for i < len(qs) {
+		switch {
+		case strings.HasPrefix(qs[i:], `.\"`):
+			end := strings.Index(qs[i+3:], `\"`)
+			if end < 0 {
+				return steps
+			}
+			i += 3 + end + 2
+			steps = append(steps, lazyStep{key: qs[i-end-2 : i-2], index: -1, end: i})
+		case qs[i] == '.':
+			j := i + 1
+			for j < len(qs) && !strings.ContainsRune(".[]|*?(){}@&,'`=<>! \"\\", rune(qs[j])) {
+				j++
+			}
+			if j < len(qs) && qs[j] == '(' {
+				return steps
+			}
+			if j == i+1 {
+				// a bare "." before "[N]" or at the root
+				if i == 0 && j < len(qs) && qs[j] == '[' {
+					i = j
+					continue
+				}
+				return steps
+			}
+			steps = append(steps, lazyStep{key: qs[i+1 : j], index: -1, end: j})
+			i = j
+		case qs[i] == '[':
+			j := i + 1
+			for j < len(qs) && qs[j] >= '0' && qs[j] <= '9' {
+				j++
+			}
+			if j == i+1 || j >= len(qs) || qs[j] != ']' {
+				return steps
+			}
+			idx, err := strconv.Atoi(qs[i+1 : j])
+			if err != nil {
+				return steps
+			}
+			steps = append(steps, lazyStep{index: idx, end: j + 1})
+			i = j + 1
+		default:
+			return steps
+		}
+	}
+	return steps
+}
+
+// manager returns a JsonManager over the value of n. Values up to
+// lazyMaterializeLimit are parsed in full; larger containers become a
+// skeleton whose big children are placeholders.
+func (d *lazyDocument) manager(n *lazyNode) (*JsonManager, error) {
+	full := d.fits(n)
+	for i, e := range d.cache {
+		if e.node == n {
+			copy(d.cache[1:i+1], d.cache[:i])
+			d.cache[0] = e
+			return e.jm, nil
+		}
+	}
+
+	var data []byte
+	var err error
+	if full {
+		data, err = d.read(n.start, n.end)
+	} else {
+		data, err = d.skeleton(n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	jm, err := newJsonManager(&decodedInput{data: data, format: InputFormatJSON}, d.order)
+	if err != nil {
+		return nil, errors.Wrapf(err, "at offset %d", n.start)
+	}
+
+	d.cache = append([]*lazyEntry{{node: n, jm: jm}}, d.cache...)
+	if len(d.cache) > lazyCacheSize {
+		d.cache = d.cache[:lazyCacheSize]
+	}
+	return jm, nil
+}
+
+// fits reports whether the value of n is small enough to be parsed in full.
+func (d *lazyDocument) fits(n *lazyNode) bool {
+	return n.kind == 0 || n.end-n.start <= lazyMaterializeLimit
+}
+
+func (d *lazyDocument) read(start, end int64) ([]byte, error) {
+	buf := make([]byte, end-start)
+	if _, err := d.file.ReadAt(buf, start); err != nil {
+		return nil, errors.Wrap(err, "invalid data")
+	}
+	return buf, nil
+}
+
+// skeleton renders the container n with its small children inlined and the
+// others replaced by a placeholder string describing them.
+func (d *lazyDocument) skeleton(n *lazyNode) ([]byte, error) {
+	if !n.indexed {
+		if err := d.scan(n); err != nil {
+			return nil, err
+		}
+	}
+	budget := lazySkeletonBudget
+	value := func(i int) (interface{}, error) {
+		size := n.ends[i] - n.starts[i]
+		if size > lazyInlineLimit || size > budget {
+			return lazyPlaceholder(n.kinds[i], size), nil
+		}
+		budget -= size
+		raw, err := d.read(n.starts[i], n.ends[i])
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid(raw) {
+			return nil, errors.Errorf("invalid json format at offset %d", n.starts[i])
+		}
+		return json.RawMessage(raw), nil
+	}
+
+	if n.kind == '{' {
+		obj := newOrderedObject()
+		for i, k := range n.keys {
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(k, v)
+		}
+		return json.Marshal(obj)
+	}
+
+	items := len(n.starts)
+	if items > lazyPreviewItems {
+		items = lazyPreviewItems
+	}
+	arr := make([]interface{}, 0, items+1)
+	for i := 0; i < items; i++ {
+		v, err := value(i)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	if rest := len(n.starts) - items; rest > 0 {
+		arr = append(arr, fmt.Sprintf("(%d more elements)", rest))
+	}
+	return json.Marshal(arr)
+}
+
+func lazyPlaceholder(kind byte, size int64) string {
+	name := "value"
+	switch kind {
+	case '{':
+		name = "object"
+	case '[':
+		name = "array"
+	}
+	return fmt.Sprintf("(lazy %s, %s)", name, formatSize(size))
+}
+
+// formatSize formats a byte count for display, e.g. "1.5 MB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// getFilteredData resolves the plain path at the start of the query through
+// the index and evaluates the rest of it against the subtree it reaches.
+func (d *lazyDocument) getFilteredData(q QueryInterface, confirm bool) (*simplejson.Json, []string, []string, error) {
+	qs := q.StringGet()
+	n, consumed, err := d.resolve(qs, confirm)
+	if err != nil {
+		return lazyError(err)
+	}
+
+	rest := qs[consumed:]
+	if !strings.HasPrefix(rest, ".") {
+		rest = "." + rest
+	}
+	// a JMESPath expression needs the whole value, which a skeleton is not
+	if isJMESPathQuery(rest) && !d.fits(n) {
+		return lazyError(errors.Errorf("%s is too large to evaluate with a JMESPath expression in lazy mode", formatSize(n.end-n.start)))
+	}
+	jm, err := d.manager(n)
+	if err != nil {
+		return lazyError(err)
+	}
+	jm.SetExpandEmbedded(d.expand)
+	return jm.GetFilteredData(NewQueryWithString(rest), confirm)
+}
+
+func lazyError(err error) (*simplejson.Json, []string, []string, error) {
+	j := simplejson.New()
+	j.SetPath(nil, err.Error())
+	return j, []string{"", ""}, []string{}, err
+}
@@ -0,0 +1,173 @@
+package jid
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const lazyFixture = `{
+  "name": "fixture",
+  "users": [
+    {"id": 1, "name": "alice", "tags": ["a", "b"]},
+    {"id": 2, "name": "bob", "tags": []}
+  ],
+  "meta": {"count": 2, "escaped \"key\"": "x,]}", "a.b": {"c": true}},
+  "userCount": 2
+}
+`
+
+func writeLazyFixture(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "big.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLazyJsonManagerMatchesEagerResults(t *testing.T) {
+	var assert = assert.New(t)
+
+	path := writeLazyFixture(t, lazyFixture)
+	lazy, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true})
+	assert.Nil(err)
+	defer lazy.Close()
+	assert.True(lazy.Lazy())
+	assert.Equal("big.json", lazy.Name())
+	assert.True(lazy.lazy.root.children[1].indexed, "second level is indexed with the root")
+
+	eager, err := NewJsonManager(bytes.NewBufferString(lazyFixture))
+	assert.Nil(err)
+
+	for _, qs := range []string{
+		".", ".name", ".users", ".users[1]", ".users[1].tags", ".users[0].na",
+		".users[*].name", ".users | length(@)", ".meta", `.meta.\"a.b\".c`,
+		".meta.", ".missing", ".users[5]", ".user",
+	} {
+		expected, _, _, _ := eager.Get(NewQueryWithString(qs), false)
+		result, _, _, _ := lazy.Get(NewQueryWithString(qs), false)
+		assert.Equal(expected, result, qs)
+	}
+
+	_, _, candidates, _ := lazy.Get(NewQueryWithString(".user"), false)
+	assert.Equal([]string{"userCount", "users"}, candidates)
+	_, _, candidates, _ = lazy.Get(NewQueryWithString(".meta."), false)
+	assert.Equal([]string{`\"a.b\"`, "count", "escaped \"key\""}, candidates)
+}
+
+func TestLazyJsonManagerSkeleton(t *testing.T) {
+	var assert = assert.New(t)
+
+	defer func(limit, inline int64, items int) {
+		lazyMaterializeLimit, lazyInlineLimit, lazyPreviewItems = limit, inline, items
+	}(lazyMaterializeLimit, lazyInlineLimit, lazyPreviewItems)
+	lazyMaterializeLimit, lazyInlineLimit, lazyPreviewItems = 64, 16, 3
+
+	path := writeLazyFixture(t, `{"small": 1, "list": [1, 2, 3, 4, 5, {"deep": "value"}], "blob": "`+
+		string(bytes.Repeat([]byte("x"), 40))+`"}`)
+	jm, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true})
+	assert.Nil(err)
+	defer jm.Close()
+
+	result, _, _, err := jm.Get(NewQueryWithString("."), false)
+	assert.Nil(err)
+	assert.Equal(`{"blob":"(lazy value, 42 B)","list":"(lazy array, 34 B)","small":1}`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(".list[5].deep"), true)
+	assert.Nil(err)
+	assert.Equal(`"value"`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(".blob"), true)
+	assert.Nil(err)
+	assert.Equal(`"`+string(bytes.Repeat([]byte("x"), 40))+`"`, result)
+}
+
+func TestLazyJsonManagerJMESPathLimit(t *testing.T) {
+	var assert = assert.New(t)
+
+	defer func(limit int64) { lazyMaterializeLimit = limit }(lazyMaterializeLimit)
+	lazyMaterializeLimit = 32
+
+	path := writeLazyFixture(t, `{"users": [{"name": "ann"}, {"name": "bob"}, {"name": "carol"}], "tags": ["a"]}`)
+	jm, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true})
+	assert.Nil(err)
+	defer jm.Close()
+
+	_, _, _, err = jm.GetFilteredData(NewQueryWithString(".users[*].name"), true)
+	assert.EqualError(err, "53 B is too large to evaluate with a JMESPath expression in lazy mode")
+	result, _, _, _ := jm.Get(NewQueryWithString(".users[*].name"), true)
+	assert.Equal(`"53 B is too large to evaluate with a JMESPath expression in lazy mode"`, result)
+
+	result, _, _, err = jm.Get(NewQueryWithString(".tags[*]"), true)
+	assert.Nil(err)
+	assert.Equal(`["a"]`, result)
+}
+
+func TestLazyJsonManagerSkeletonArray(t *testing.T) {
+	var assert = assert.New(t)
+
+	defer func(limit int64, items int) {
+		lazyMaterializeLimit, lazyPreviewItems = limit, items
+	}(lazyMaterializeLimit, lazyPreviewItems)
+	lazyMaterializeLimit, lazyPreviewItems = 8, 3
+
+	path := writeLazyFixture(t, `[10, 20, 30, 40, 50]`)
+	jm, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true})
+	assert.Nil(err)
+	defer jm.Close()
+
+	result, _, _, _ := jm.Get(NewQueryWithString("."), false)
+	assert.Equal(`[10,20,30,"(2 more elements)"]`, result)
+	result, _, _, _ = jm.Get(NewQueryWithString(".[4]"), false)
+	assert.Equal(`50`, result)
+}
+
+func TestLazyJsonManagerProgress(t *testing.T) {
+	var assert = assert.New(t)
+
+	path := writeLazyFixture(t, lazyFixture)
+	var done, total int64
+	jm, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true, Progress: func(d, t int64) {
+		done, total = d, t
+	}})
+	assert.Nil(err)
+	jm.Close()
+	assert.Equal(int64(len(lazyFixture)), total)
+	assert.Equal(total, done)
+}
+
+func TestLazyJsonManagerWithError(t *testing.T) {
+	var assert = assert.New(t)
+
+	for _, content := range []string{`{"a": 1,}`, `[1,,2]`, `{"a"}`, `{"a": [1, 2}`, "{}\n{}\n", ``, `[1, 2`} {
+		path := writeLazyFixture(t, content)
+		jm, err := NewJsonManagerFromFile(path, &InputOption{Lazy: true})
+		assert.Nil(jm, content)
+		if assert.NotNil(err, content) {
+			assert.Regexp("invalid json format", err.Error(), content)
+		}
+	}
+
+	_, err := NewJsonManagerWithOption(bytes.NewBufferString(`{}`), &InputOption{Lazy: true})
+	assert.Regexp("lazy loading needs a file", err.Error())
+
+	path := writeLazyFixture(t, `{}`)
+	_, err = NewJsonManagerFromFile(path, &InputOption{Lazy: true, Format: InputFormatYAML})
+	assert.Regexp("supports json input only", err.Error())
+}
+
+func TestLazyPathPrefix(t *testing.T) {
+	var assert = assert.New(t)
+
+	assert.Equal([]lazyStep{
+		{key: "users", index: -1, end: 6},
+		{index: 3, end: 9},
+		{key: "a.b", index: -1, end: 17},
+	}, lazyPathPrefix(`.users[3].\"a.b\"[*].x`))
+	assert.Equal([]lazyStep{{index: 0, end: 4}}, lazyPathPrefix(".[0]"))
+	assert.Equal([]lazyStep{{key: "a", index: -1, end: 2}}, lazyPathPrefix(".a | keys(@)"))
+	assert.Nil(lazyPathPrefix(". | length(@)"))
+}
@@ -0,0 +1,217 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// OutputFormat is the format the selected result is printed in.
+type OutputFormat string
+
+const (
+	OutputJSON   OutputFormat = "json"
+	OutputYAML   OutputFormat = "yaml"
+	OutputCSV    OutputFormat = "csv"
+	OutputTSV    OutputFormat = "tsv"
+	OutputGron   OutputFormat = "gron"
+	OutputGo     OutputFormat = "go"
+	OutputTS     OutputFormat = "ts"
+	OutputSchema OutputFormat = "schema"
+	// OutputTemplate executes OutputOption.Template. It is chosen with
+	// --template rather than --output.
+	OutputTemplate OutputFormat = "template"
+	// OutputCanonical is RFC 8785 canonical JSON, chosen with --canonical.
+	OutputCanonical OutputFormat = "canonical"
+)
+
+var outputFormats = []OutputFormat{OutputJSON, OutputYAML, OutputCSV, OutputTSV, OutputGron, OutputGo, OutputTS, OutputSchema}
+
+// OutputOption controls how Render prints a result.
+type OutputOption struct {
+	Format OutputFormat
+	// Columns picks and orders the columns of CSV/TSV output. Empty means
+	// every key, in the order the keys first appear.
+	Columns []string
+	// Template is executed with the result for OutputTemplate.
+	Template *template.Template
+}
+
+// ParseOutputFormat converts a --output value to an OutputFormat.
+// An empty string means JSON.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	f := OutputFormat(strings.ToLower(strings.TrimSpace(s)))
+	if f == "" {
+		return OutputJSON, nil
+	}
+	switch f {
+	case "yml":
+		return OutputYAML, nil
+	case "typescript":
+		return OutputTS, nil
+	}
+	for _, known := range outputFormats {
+		if f == known {
+			return f, nil
+		}
+	}
+	return OutputJSON, errors.Errorf("unknown output format: %s", s)
+}
+
+// Render returns the result of q as described by opt. JSON is pretty printed.
+func (jm *JsonManager) Render(q QueryInterface, opt *OutputOption, confirm bool) (string, error) {
+	f := opt.Format
+	if f == OutputJSON || f == "" {
+		s, _, _, err := jm.GetPretty(q, confirm)
+		return s, err
+	}
+	j, _, _, _ := jm.GetFilteredData(q, confirm)
+	v := j.Interface()
+	switch f {
+	case OutputYAML:
+		return RenderYAML(v, jm.keyOrder())
+	case OutputCSV, OutputTSV:
+		return renderTable(v, jm.keyOrder(), opt.Columns, f == OutputTSV)
+	case OutputGron:
+		path, _ := queryKeys(q.StringGet())
+		return renderGron(v, jm.keyOrder(), path), nil
+	case OutputGo:
+		return RenderGoTypes(v, jm.keyOrder(), typeRootName(q.StringGet())), nil
+	case OutputTS:
+		return RenderTSTypes(v, jm.keyOrder(), typeRootName(q.StringGet())), nil
+	case OutputSchema:
+		return RenderSchema(v, jm.keyOrder()), nil
+	case OutputTemplate:
+		return renderTemplate(opt.Template, v, jm.keyOrder())
+	case OutputCanonical:
+		return RenderCanonical(v)
+	}
+	return "", errors.Errorf("unknown output format %q", f)
+}
+
+// maxOrderedKeys is the largest object whose key order is remembered; bigger
+// objects (usually maps keyed by id) are rendered with sorted keys.
+const maxOrderedKeys = 1000
+
+// keyOrder holds the keys of every object of a loaded document in document
+// order, found by the identity of the object's map. Query results share their
+// objects with the document; objects built by a query, such as a multi-select
+// hash, are rendered with sorted keys.
+type keyOrder map[uintptr][]string
+
+func objectID(m map[string]interface{}) uintptr {
+	return reflect.ValueOf(m).Pointer()
+}
+
+// keys returns the keys of m in document order.
+func (o keyOrder) keys(m map[string]interface{}) []string {
+	if ordered, ok := o[objectID(m)]; ok && len(ordered) == len(m) {
+		return ordered
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scanKeyOrder records the key order of every object in the JSON text data
+// for the objects of trees, which are decodings of data.
+func scanKeyOrder(data []byte, trees ...interface{}) keyOrder {
+	type frame struct {
+		object  bool
+		wantKey bool
+		key     string
+		index   int
+		keys    []string
+		values  []interface{} // the container in each tree
+	}
+	next := func(f *frame) {
+		if f == nil {
+			return
+		}
+		if f.object {
+			f.wantKey = true
+		} else {
+			f.index++
+		}
+	}
+	order := keyOrder{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	var stack []*frame
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return order
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				values := trees
+				if top != nil {
+					values = make([]interface{}, len(trees))
+					for i, v := range top.values {
+						switch c := v.(type) {
+						case map[string]interface{}:
+							values[i] = c[top.key]
+						case []interface{}:
+							if top.index < len(c) {
+								values[i] = c[top.index]
+							}
+						}
+					}
+				}
+				stack = append(stack, &frame{object: t == '{', wantKey: t == '{', values: values})
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if top.object && len(top.keys) <= maxOrderedKeys {
+				for _, v := range top.values {
+					if m, ok := v.(map[string]interface{}); ok && len(m) == len(top.keys) {
+						order[objectID(m)] = top.keys
+					}
+				}
+			}
+			if len(stack) > 0 {
+				next(stack[len(stack)-1])
+			}
+		case string:
+			if top != nil && top.wantKey {
+				top.key = t
+				top.keys = append(top.keys, t)
+				top.wantKey = false
+				continue
+			}
+			next(top)
+		default:
+			next(top)
+		}
+	}
+}
+
+// keyOrder returns the key order of the loaded document, scanned when it was
+// loaded with InputOption.KeyOrder so its text need not be kept. A lazy
+// document uses the subtree it materialized last.
+func (jm *JsonManager) keyOrder() keyOrder {
+	if jm.lazy != nil {
+		if len(jm.lazy.cache) > 0 {
+			return jm.lazy.cache[0].jm.keyOrder()
+		}
+		return nil
+	}
+	if jm.expanded {
+		return jm.embedded.order
+	}
+	return jm.order
+}
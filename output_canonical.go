@@ -0,0 +1,159 @@
+package jid
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// RenderCanonical writes v in the JSON Canonicalization Scheme of RFC 8785:
+// no whitespace, object keys sorted by their UTF-16 code units, strings with
+// the minimal escaping of ECMAScript's JSON.stringify and numbers formatted
+// as ECMAScript formats an IEEE 754 double. Equal values give equal bytes, so
+// the output can be hashed and diffed.
+func RenderCanonical(v interface{}) (string, error) {
+	var sb strings.Builder
+	if err := writeCanonical(&sb, v); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeCanonical(sb *strings.Builder, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(t))
+	case string:
+		writeCanonicalString(sb, t)
+	case json.Number:
+		f, err := strconv.ParseFloat(t.String(), 64)
+		if err != nil {
+			return errors.Errorf("number %s cannot be represented in canonical JSON", t)
+		}
+		sb.WriteString(canonicalNumber(f))
+	case float64:
+		if math.IsInf(t, 0) || math.IsNaN(t) {
+			return errors.Errorf("number %v cannot be represented in canonical JSON", t)
+		}
+		sb.WriteString(canonicalNumber(t))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeCanonicalString(sb, k)
+			sb.WriteByte(':')
+			if err := writeCanonical(sb, t[k]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, e := range t {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := writeCanonical(sb, e); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	default:
+		return errors.Errorf("unexpected value %T in canonical JSON", v)
+	}
+	return nil
+}
+
+// lessUTF16 orders strings by their UTF-16 code units, as RFC 8785 sorts
+// object keys. It differs from byte order for characters above U+FFFF.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// writeCanonicalString escapes only the quote, the backslash and the control
+// characters; the latter use the short forms where JSON has them.
+func writeCanonicalString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				sb.WriteString(`\u00`)
+				sb.WriteByte("0123456789abcdef"[r>>4])
+				sb.WriteByte("0123456789abcdef"[r&0xf])
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// canonicalNumber formats f like ECMAScript's Number.prototype.toString: the
+// shortest digits that round trip, written in plain notation when the
+// decimal exponent is in [-6, 21) and in exponent notation otherwise.
+func canonicalNumber(f float64) string {
+	if f == 0 {
+		return "0" // also -0
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// shortest digits as d.ddde±x
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	k, n := len(digits), x+1 // n: position of the decimal point
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	s := digits[:1]
+	if k > 1 {
+		s += "." + digits[1:]
+	}
+	if n-1 > 0 {
+		return sign + s + "e+" + strconv.Itoa(n-1)
+	}
+	return sign + s + "e" + strconv.Itoa(n-1)
+}
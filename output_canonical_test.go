@@ -0,0 +1,56 @@
+package jid
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCanonical(t *testing.T) {
+	var assert = assert.New(t)
+
+	// the example of RFC 8785 section 3.2.2
+	jm, err := NewJsonManager(bytes.NewBufferString(`{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`))
+	assert.Nil(err)
+	s, err := jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputCanonical}, true)
+	assert.Nil(err)
+	assert.Equal(`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, s)
+
+	// keys are sorted by UTF-16 code units: U+1F600 (D83D DE00) before U+FB33
+	s, err = RenderCanonical(map[string]interface{}{"\ufb33": 1.0, "\U0001f600": 2.0, "a": 3.0, "<&>": "<&>"})
+	assert.Nil(err)
+	assert.Equal("{\"<&>\":\"<&>\",\"a\":3,\"\U0001f600\":2,\"\ufb33\":1}", s)
+
+	_, err = RenderCanonical(math.Inf(1))
+	assert.NotNil(err)
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	var assert = assert.New(t)
+
+	// values of RFC 8785 appendix B
+	for bits, expected := range map[uint64]string{
+		0x0000000000000000: "0",
+		0x8000000000000000: "0",
+		0x0000000000000001: "5e-324",
+		0x8000000000000001: "-5e-324",
+		0x7fefffffffffffff: "1.7976931348623157e+308",
+		0x4340000000000000: "9007199254740992",
+		0x4430000000000000: "295147905179352830000",
+		0x44b52d02c7e14af5: "9.999999999999997e+22",
+		0x44b52d02c7e14af6: "1e+23",
+		0x444b1ae4d6e2ef50: "1e+21",
+		0x444b1ae4d6e2ef4e: "999999999999999700000",
+		0x3eb0c6f7a0b5ed8d: "0.000001",
+		0x3eb0c6f7a0b5ed8c: "9.999999999999997e-7",
+		0x41b3de4355555553: "333333333.3333332",
+	} {
+		assert.Equal(expected, canonicalNumber(math.Float64frombits(bits)), "%x", bits)
+	}
+}
@@ -0,0 +1,113 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tsvEscaper keeps every TSV row on one line. TSV has no quoting, so tabs and
+// line breaks inside a cell are written as escape sequences.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// renderTable renders v, an array of objects, as CSV (or TSV when tsv is set)
+// with a header row. The columns are the given ones in that order, or else the
+// union of the keys of all elements in the order they are first seen.
+func renderTable(v interface{}, order keyOrder, columns []string, tsv bool) (string, error) {
+	name := "csv"
+	if tsv {
+		name = "tsv"
+	}
+	a, ok := v.([]interface{})
+	if !ok {
+		return "", errors.Errorf("%s output needs an array of objects", name)
+	}
+	rows := make([]map[string]interface{}, len(a))
+	for i, e := range a {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return "", errors.Errorf("%s output needs an array of objects: element %d is not an object", name, i)
+		}
+		rows[i] = m
+	}
+	if len(columns) == 0 {
+		columns = tableColumns(rows, order)
+	}
+
+	records := make([][]string, 0, len(rows)+1)
+	records = append(records, columns)
+	for _, m := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			cell, err := tableCell(m[c])
+			if err != nil {
+				return "", err
+			}
+			record[i] = cell
+		}
+		records = append(records, record)
+	}
+
+	if tsv {
+		var sb strings.Builder
+		for _, record := range records {
+			for i, cell := range record {
+				if i > 0 {
+					sb.WriteByte('\t')
+				}
+				sb.WriteString(tsvEscaper.Replace(cell))
+			}
+			sb.WriteByte('\n')
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return "", errors.Wrap(err, "failure csv encode")
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// tableColumns returns the union of the keys of rows in first-seen order.
+func tableColumns(rows []map[string]interface{}, order keyOrder) []string {
+	var columns []string
+	seen := map[string]bool{}
+	for _, m := range rows {
+		for _, k := range order.keys(m) {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	return columns
+}
+
+// tableCell formats one value: null and missing values are empty, strings and
+// scalars are written as is and arrays and objects as compact JSON.
+func tableCell(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		if t {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", errors.Wrap(err, "failure json encode")
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
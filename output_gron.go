@@ -0,0 +1,108 @@
+package jid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gronRoot names the document at the start of every gron line.
+const gronRoot = "json"
+
+// renderGron flattens v into one assignment per line, parents before their
+// children, e.g. json.users[0].name = "x";. Empty and non-empty containers
+// are assigned {} or [] so decodeGron can rebuild the document. prefix is the
+// path of v within the document.
+func renderGron(v interface{}, order keyOrder, prefix gronPath) string {
+	var sb strings.Builder
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(&sb, "%s = {};\n", path)
+			for _, k := range order.keys(t) {
+				walk(path+gronKey(k), t[k])
+			}
+		case []interface{}:
+			fmt.Fprintf(&sb, "%s = [];\n", path)
+			for i, e := range t {
+				walk(fmt.Sprintf("%s[%d]", path, i), e)
+			}
+		default:
+			fmt.Fprintf(&sb, "%s = %s;\n", path, compactJSON(v))
+		}
+	}
+	walk(prefix.String(), v)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// gronKey writes an object key of a gron path: .key for an identifier and
+// ["key"], quoted as a JSON string, for any other key.
+func gronKey(k string) string {
+	if reIdentifier.MatchString(k) {
+		return "." + k
+	}
+	return "[" + compactJSON(k) + "]"
+}
+
+// jidKey writes an object key the way the query line does: keys containing
+// a dot, and keys that would end a gron path early, are wrapped in \"...\".
+func jidKey(k string) string {
+	if k == "" || strings.ContainsAny(k, `.[]= ;"\`) {
+		return `\"` + k + `\"`
+	}
+	return k
+}
+
+// queryPath returns the query line path of the result of qs (".a[0]"), or ""
+// when the result is not a single place in the document.
+func queryPath(qs string) string {
+	p, ok := queryKeys(qs)
+	if !ok {
+		return ""
+	}
+	var sb strings.Builder
+	for _, e := range p {
+		switch t := e.(type) {
+		case string:
+			sb.WriteString("." + jidKey(t))
+		case int:
+			fmt.Fprintf(&sb, "[%d]", t)
+		}
+	}
+	return sb.String()
+}
+
+// queryKeys returns the keys and indexes leading to the result of qs, and
+// false when the result is not a single place in the document.
+func queryKeys(qs string) (gronPath, bool) {
+	if reFromJSON.MatchString(qs) {
+		return nil, false
+	}
+	n, err := parseJidQuery(qs)
+	if err != nil {
+		return nil, false
+	}
+	var walk func(n *queryNode) (gronPath, bool)
+	walk = func(n *queryNode) (gronPath, bool) {
+		switch n.kind {
+		case qnIdentity, qnCurrent:
+			return gronPath{}, true
+		case qnField:
+			return gronPath{n.value.(string)}, true
+		case qnIndex:
+			if n.value.(int) < 0 {
+				return nil, false
+			}
+			return gronPath{n.value.(int)}, true
+		case qnSubexpression, qnIndexExpression:
+			l, ok := walk(n.children[0])
+			if !ok {
+				return nil, false
+			}
+			r, ok := walk(n.children[1])
+			return append(l, r...), ok
+		}
+		return nil, false
+	}
+	return walk(n)
+}
@@ -0,0 +1,113 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// schemaDraft is the JSON Schema dialect of RenderSchema.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaKinds maps the kinds of a typeShape to JSON Schema types, in the
+// order they are listed.
+var schemaKinds = []struct{ kind, typ string }{
+	{"object", "object"}, {"array", "array"}, {"string", "string"},
+	{"int", "integer"}, {"float", "number"}, {"bool", "boolean"}, {"null", "null"},
+}
+
+// orderedObject is a JSON object that keeps its keys in insertion order.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedObject() *orderedObject {
+	return &orderedObject{values: map[string]interface{}{}}
+}
+
+func (o *orderedObject) set(k string, v interface{}) {
+	if _, ok := o.values[k]; !ok {
+		o.keys = append(o.keys, k)
+	}
+	o.values[k] = v
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(jsonQuote(k))
+		buf.WriteByte(':')
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1) // the newline Encode adds
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// RenderSchema infers a draft 2020-12 JSON Schema from v. All elements of an
+// array are merged into one items schema, keys missing from some objects are
+// not required and string fields with few distinct values become enums.
+func RenderSchema(v interface{}, order keyOrder) string {
+	s := newTypeShape()
+	s.add(v, order)
+	root := shapeSchema(s)
+	root.keys = append([]string{"$schema"}, root.keys...)
+	root.values["$schema"] = schemaDraft
+	return prettyJSON(root)
+}
+
+func shapeSchema(s *typeShape) *orderedObject {
+	o := newOrderedObject()
+	var types []string
+	for _, k := range schemaKinds {
+		// an integer next to a float is a number
+		if s.kinds[k.kind] && !(k.kind == "int" && s.kinds["float"]) {
+			types = append(types, k.typ)
+		}
+	}
+	switch len(types) {
+	case 0:
+		return o
+	case 1:
+		o.set("type", types[0])
+	default:
+		o.set("type", types)
+	}
+
+	if enum := s.enum(); enum != nil {
+		values := make([]interface{}, 0, len(enum)+1)
+		for _, e := range enum {
+			values = append(values, e)
+		}
+		if s.kinds["null"] {
+			values = append(values, nil)
+		}
+		o.set("enum", values)
+	}
+	if s.kinds["object"] {
+		props := newOrderedObject()
+		var required []string
+		for _, k := range s.keys {
+			props.set(k, shapeSchema(s.fields[k]))
+			if !s.optional(k) {
+				required = append(required, k)
+			}
+		}
+		o.set("properties", props)
+		if len(required) > 0 {
+			o.set("required", required)
+		}
+	}
+	if s.kinds["array"] && s.elem != nil && len(s.elem.kinds) > 0 {
+		o.set("items", shapeSchema(s.elem))
+	}
+	return o
+}
@@ -0,0 +1,86 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderSchema(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(`{"orders": [
+  {"id": 1, "status": "paid", "total": 9.5, "tags": ["a"], "note": null},
+  {"id": 2, "status": "open", "total": 3, "tags": []},
+  {"id": 3, "status": "paid", "total": 4, "tags": [], "note": "gift", "customer": {"name": "Ann"}}
+]}`), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+
+	s, err := jm.Render(NewQueryWithString(".orders"), &OutputOption{Format: OutputSchema}, true)
+	assert.Nil(err)
+	assert.Equal(`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "array",
+  "items": {
+    "type": "object",
+    "properties": {
+      "id": {
+        "type": "integer"
+      },
+      "status": {
+        "type": "string",
+        "enum": [
+          "paid",
+          "open"
+        ]
+      },
+      "total": {
+        "type": "number"
+      },
+      "tags": {
+        "type": "array",
+        "items": {
+          "type": "string"
+        }
+      },
+      "note": {
+        "type": [
+          "string",
+          "null"
+        ]
+      },
+      "customer": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "name"
+        ]
+      }
+    },
+    "required": [
+      "id",
+      "status",
+      "total",
+      "tags"
+    ]
+  }
+}`, s)
+}
+
+func TestRenderSchemaScalars(t *testing.T) {
+	var assert = assert.New(t)
+
+	assert.Equal("{\n  \"$schema\": \"https://json-schema.org/draft/2020-12/schema\",\n  \"type\": \"string\"\n}", RenderSchema("x", nil))
+
+	// distinct strings are not an enum
+	s := RenderSchema([]interface{}{"a", "b"}, nil)
+	assert.NotContains(s, "enum")
+
+	s = RenderSchema([]interface{}{}, nil)
+	assert.NotContains(s, "items")
+}
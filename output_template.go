@@ -0,0 +1,156 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ParseTemplate parses a Go text/template for OutputTemplate. Besides the
+// builtins it can use json, yaml, join, pad and default; see templateFuncs.
+func ParseTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs(keyOrder{})).Parse(text)
+}
+
+// templateFuncs returns the helper functions of templates. order is the key
+// order of the document, used by json and yaml.
+func templateFuncs(order keyOrder) template.FuncMap {
+	return template.FuncMap{
+		// {{json .}} writes a value as compact JSON
+		"json": func(v interface{}) string {
+			return compactJSON(orderedValue(v, order))
+		},
+		// {{yaml .}} writes a value as a YAML document
+		"yaml": func(v interface{}) (string, error) {
+			return RenderYAML(v, order)
+		},
+		// {{.tags | join ", "}} joins the elements of an array
+		"join": func(sep string, v interface{}) (string, error) {
+			a, ok := v.([]interface{})
+			if !ok {
+				return "", errors.Errorf("join needs an array, got %s", compactJSON(v))
+			}
+			items := make([]string, 0, len(a))
+			for _, e := range a {
+				s, err := tableCell(e)
+				if err != nil {
+					return "", err
+				}
+				items = append(items, s)
+			}
+			return strings.Join(items, sep), nil
+		},
+		// {{.name | pad 10}} pads a value with spaces to a width; a negative
+		// width aligns it to the right
+		"pad": func(width int, v interface{}) (string, error) {
+			s, err := tableCell(v)
+			if err != nil {
+				return "", err
+			}
+			right := width < 0
+			if right {
+				width = -width
+			}
+			fill := strings.Repeat(" ", maxInt(width-utf8.RuneCountInString(s), 0))
+			if right {
+				return fill + s, nil
+			}
+			return s + fill, nil
+		},
+		// {{if gt (num .price) 9.5}} and {{if gt (int .age) 30}} compare
+		// numbers; a number of the document is only equal to strings
+		"num": func(v interface{}) (float64, error) {
+			return templateNumber(v)
+		},
+		"int": func(v interface{}) (int64, error) {
+			f, err := templateNumber(v)
+			return int64(f), err
+		},
+		// {{.email | default "-"}} replaces a missing, null or empty value
+		"default": func(def interface{}, v interface{}) interface{} {
+			switch t := v.(type) {
+			case nil:
+				return def
+			case string:
+				if t == "" {
+					return def
+				}
+			case []interface{}:
+				if len(t) == 0 {
+					return def
+				}
+			case map[string]interface{}:
+				if len(t) == 0 {
+					return def
+				}
+			}
+			return v
+		},
+	}
+}
+
+// renderTemplate executes t with v, the result of the query, as its data.
+func renderTemplate(t *template.Template, v interface{}, order keyOrder) (string, error) {
+	if t == nil {
+		return "", errors.New("template output needs a template")
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Funcs(templateFuncs(order)).Execute(&buf, v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateNumber converts a number of the document, which keeps its text as
+// a json.Number, to a float64 for the num and int helpers.
+func templateNumber(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return t.Float64()
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case string:
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, errors.Errorf("%s is not a number", compactJSON(v))
+}
+
+// orderedValue returns v with its objects replaced by orderedObjects that
+// marshal their keys in document order.
+func orderedValue(v interface{}, order keyOrder) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		o := newOrderedObject()
+		for _, k := range order.keys(t) {
+			o.set(k, orderedValue(t[k], order))
+		}
+		return o
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = orderedValue(e, order)
+		}
+		return a
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,56 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(`{"users": [
+  {"name": "ann", "id": 1, "tags": ["a", "b"], "email": "ann@example.com", "score": 2.50, "ref": 12345678901234567890},
+  {"name": "bob", "id": 22, "tags": [], "email": null, "score": 31}
+]}`), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+	render := func(text string) (string, error) {
+		tmpl, err := ParseTemplate("t", text)
+		assert.Nil(err)
+		return jm.Render(NewQueryWithString(".users"), &OutputOption{Format: OutputTemplate, Template: tmpl}, true)
+	}
+
+	s, err := render(`{{range .}}{{.name}}{{"\t"}}{{.id}}{{"\n"}}{{end}}`)
+	assert.Nil(err)
+	assert.Equal("ann\t1\nbob\t22\n", s)
+
+	s, err = render(`{{range .}}{{.name | pad 5}}|{{.id | pad -3}}|{{.tags | join ","}}|{{.email | default "-"}}|{{.phone | default "?"}}{{"\n"}}{{end}}`)
+	assert.Nil(err)
+	assert.Equal("ann  |  1|a,b|ann@example.com|?\nbob  | 22||-|?\n", s)
+
+	// numbers keep their text; num and int compare them whatever their form
+	s, err = render(`{{range .}}{{.score}} {{if gt (num .score) 30.0}}{{.name}}{{end}}{{if eq (int .id) 1}}{{.id}}{{end}}{{if lt (int .score) 3}}low{{end}};{{end}}`)
+	assert.Nil(err)
+	assert.Equal("2.50 1low;31 bob;", s)
+
+	_, err = render(`{{num (index . 1).email}}`)
+	assert.Contains(err.Error(), `error calling num: null is not a number`)
+
+	s, err = render(`{{json (index . 0)}}`)
+	assert.Nil(err)
+	assert.Equal(`{"name":"ann","id":1,"tags":["a","b"],"email":"ann@example.com","score":2.50,"ref":12345678901234567890}`, s)
+
+	s, err = render(`{{yaml (index . 1)}}`)
+	assert.Nil(err)
+	assert.Equal("name: bob\nid: 22\ntags: []\nemail: null\nscore: 31", s)
+
+	_, err = render(`{{.name}}`)
+	assert.Contains(err.Error(), "can't evaluate field name")
+
+	_, err = render(`{{join "," (index . 0)}}`)
+	assert.Contains(err.Error(), `join needs an array, got {"email":"ann@example.com","id":1,"name":"ann","ref":12345678901234567890,"score":2.50,"tags":["a","b"]}`)
+
+	_, err = ParseTemplate("t", `{{range .}}`)
+	assert.NotNil(err)
+}
@@ -0,0 +1,165 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	var assert = assert.New(t)
+
+	f, err := ParseOutputFormat("")
+	assert.Nil(err)
+	assert.Equal(OutputJSON, f)
+
+	f, err = ParseOutputFormat("YML")
+	assert.Nil(err)
+	assert.Equal(OutputYAML, f)
+
+	_, err = ParseOutputFormat("ini")
+	assert.Regexp("unknown output format", err.Error())
+}
+
+func TestScanKeyOrder(t *testing.T) {
+	var assert = assert.New(t)
+
+	data := []byte(`{"z":1,"a":{"y":[{"q":1,"p":2}],"b":"x"},"m":[{"p":3,"q":4}]}`)
+	var v map[string]interface{}
+	assert.Nil(json.Unmarshal(data, &v))
+	order := scanKeyOrder(data, v)
+	a := v["a"].(map[string]interface{})
+	assert.Equal([]string{"z", "a", "m"}, order.keys(v))
+	assert.Equal([]string{"y", "b"}, order.keys(a))
+	// each object keeps its own order, whatever the order of others with the same keys
+	assert.Equal([]string{"q", "p"}, order.keys(a["y"].([]interface{})[0].(map[string]interface{})))
+	assert.Equal([]string{"p", "q"}, order.keys(v["m"].([]interface{})[0].(map[string]interface{})))
+	assert.Equal([]string{"p", "q"}, order.keys(map[string]interface{}{"q": 1, "p": 1}), "objects not in the document are sorted")
+}
+
+func TestRenderYAML(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(`{
+  "replicaCount": 2,
+  "image": {"repository": "nginx", "tag": "1.25", "pullPolicy": "IfNotPresent"},
+  "enabled": "yes",
+  "ratio": 0.5,
+  "annotations": {},
+  "args": [],
+  "script": "set -e\necho done\n",
+  "missing": null
+}`), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+
+	s, err := jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputYAML}, true)
+	assert.Nil(err)
+	assert.Equal(`replicaCount: 2
+image:
+  repository: nginx
+  tag: "1.25"
+  pullPolicy: IfNotPresent
+enabled: "yes"
+ratio: 0.5
+annotations: {}
+args: []
+script: |
+  set -e
+  echo done
+missing: null`, s)
+
+	s, _ = jm.Render(NewQueryWithString(".image"), &OutputOption{Format: OutputYAML}, true)
+	assert.Equal("repository: nginx\ntag: \"1.25\"\npullPolicy: IfNotPresent", s)
+
+	s, _ = jm.Render(NewQueryWithString(".image"), &OutputOption{Format: OutputJSON}, true)
+	assert.Equal("{\n  \"pullPolicy\": \"IfNotPresent\",\n  \"repository\": \"nginx\",\n  \"tag\": \"1.25\"\n}", s)
+}
+
+func TestRenderKeyOrder(t *testing.T) {
+	var assert = assert.New(t)
+
+	render := func(data string, format InputFormat, q string, out OutputFormat) string {
+		jm, err := NewJsonManagerWithOption(bytes.NewBufferString(data), &InputOption{Format: format, KeyOrder: true})
+		assert.Nil(err)
+		s, err := jm.Render(NewQueryWithString(q), &OutputOption{Format: out}, true)
+		assert.Nil(err)
+		return s
+	}
+
+	assert.Equal("zeta: 1\nalpha: 2", render("zeta: 1\nalpha: 2\n", InputFormatYAML, ".", OutputYAML))
+	assert.Equal("base:\n  zeta: 1\n  alpha: 2\nitem:\n  zeta: 1\n  alpha: 3\n  mid: true",
+		render("base: &b\n  zeta: 1\n  alpha: 2\nitem:\n  <<: *b\n  alpha: 3\n  mid: true\n", InputFormatYAML, ".", OutputYAML))
+	assert.Equal("zeta,alpha\n1,2", render("zeta,alpha\n1,2\n", InputFormatCSV, ".", OutputCSV))
+	assert.Equal("zeta: 1\nalpha:\n  z: 1\n  a: 2", render("zeta = 1\n[alpha]\nz = 1\na = 2\n", InputFormatTOML, ".", OutputYAML))
+	assert.Equal("doc:\n  zeta: \"1\"\n  alpha: \"2\"", render("<doc><zeta>1</zeta><alpha>2</alpha></doc>", InputFormatXML, ".", OutputYAML))
+
+	// every object keeps its own order, also through a JMESPath projection
+	data := `{"items": [{"b": 1, "a": 2}, {"a": 3, "b": 4}]}`
+	assert.Equal("- b: 1\n  a: 2\n- a: 3\n  b: 4", render(data, InputFormatJSON, ".items", OutputYAML))
+	assert.Equal("- b: 1\n  a: 2\n- a: 3\n  b: 4", render(data, InputFormatJSON, ".items[?b > `0`]", OutputYAML))
+
+	// without KeyOrder the keys are sorted
+	jm, err := NewJsonManager(bytes.NewBufferString(data))
+	assert.Nil(err)
+	s, _ := jm.Render(NewQueryWithString(".items[0]"), &OutputOption{Format: OutputYAML}, true)
+	assert.Equal("a: 2\nb: 1", s)
+}
+
+func TestRenderTable(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManager(bytes.NewBufferString(`{"users": [
+  {"id": 1, "name": "Ann, Jr.", "tags": ["admin"]},
+  {"id": 2, "name": "Bob", "active": true, "note": "line\tone\nline two"},
+  {"name": "Cy", "id": 3, "meta": {"age": null}, "active": null}
+], "name": "x"}`))
+	assert.Nil(err)
+
+	s, err := jm.Render(NewQueryWithString(".users"), &OutputOption{Format: OutputCSV}, true)
+	assert.Nil(err)
+	assert.Equal(`id,name,tags,active,note,meta
+1,"Ann, Jr.","[""admin""]",,,
+2,Bob,,true,"line	one
+line two",
+3,Cy,,,,"{""age"":null}"`, s)
+
+	s, err = jm.Render(NewQueryWithString(".users"), &OutputOption{Format: OutputTSV, Columns: []string{"note", "id", "missing"}}, true)
+	assert.Nil(err)
+	assert.Equal("note\tid\tmissing\n\t1\t\nline\\tone\\nline two\t2\t\n\t3\t", s)
+
+	_, err = jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputCSV}, true)
+	assert.Regexp("csv output needs an array of objects", err.Error())
+
+	_, err = jm.Render(NewQueryWithString(".users[*].name"), &OutputOption{Format: OutputTSV}, true)
+	assert.Regexp("tsv output needs an array of objects: element 0", err.Error())
+}
+
+func TestRenderGron(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(`{"users": [{"name": "Ann", "a.b": {}, "note": "<x>\n"}], "n": 1e3}`), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+
+	s, err := jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputGron}, true)
+	assert.Nil(err)
+	assert.Equal(`json = {};
+json.users = [];
+json.users[0] = {};
+json.users[0].name = "Ann";
+json.users[0]["a.b"] = {};
+json.users[0].note = "<x>\n";
+json.n = 1e3;`, s)
+
+	// paths continue the query, so a line can be pasted back into it
+	s, _ = jm.Render(NewQueryWithString(".users[0].name"), &OutputOption{Format: OutputGron}, true)
+	assert.Equal(`json.users[0].name = "Ann";`, s)
+
+	s, _ = jm.Render(NewQueryWithString(".users[*].name"), &OutputOption{Format: OutputGron}, true)
+	assert.Equal("json = [];\njson[0] = \"Ann\";", s)
+
+	back, err := decodeGron([]byte(`json.users[0].\"a.b\" = {};`))
+	assert.Nil(err)
+	assert.Equal(`{"users":[{"a.b":{}}]}`, string(back))
+}
@@ -0,0 +1,383 @@
+package jid
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+)
+
+// typeShape is what is known about the values found at one place in the
+// result: the JSON kinds seen, the fields of the objects and how many of
+// the objects had each field, and the shape of the array elements.
+type typeShape struct {
+	kinds   map[string]bool // null, bool, int, float, string, object, array
+	keys    []string
+	fields  map[string]*typeShape
+	seen    map[string]int
+	objects int
+	elem    *typeShape
+	name    string // type name of an object shape
+	// the distinct strings seen, in order, until there are more than
+	// maxEnumValues of them
+	values  []string
+	strings int
+}
+
+// maxEnumValues is the most distinct strings a field can have to be
+// considered an enum.
+const maxEnumValues = 10
+
+func newTypeShape() *typeShape {
+	return &typeShape{kinds: map[string]bool{}, fields: map[string]*typeShape{}, seen: map[string]int{}}
+}
+
+// add merges one value into the shape.
+func (s *typeShape) add(v interface{}, order keyOrder) {
+	switch t := v.(type) {
+	case nil:
+		s.kinds["null"] = true
+	case bool:
+		s.kinds["bool"] = true
+	case string:
+		s.kinds["string"] = true
+		s.strings++
+		if len(s.values) <= maxEnumValues && !containsString(s.values, t) {
+			s.values = append(s.values, t)
+		}
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			s.kinds["int"] = true
+		} else {
+			s.kinds["float"] = true
+		}
+	case float64:
+		if t == float64(int64(t)) {
+			s.kinds["int"] = true
+		} else {
+			s.kinds["float"] = true
+		}
+	case map[string]interface{}:
+		s.kinds["object"] = true
+		s.objects++
+		for _, k := range order.keys(t) {
+			f, ok := s.fields[k]
+			if !ok {
+				f = newTypeShape()
+				s.fields[k] = f
+				s.keys = append(s.keys, k)
+			}
+			f.add(t[k], order)
+			s.seen[k]++
+		}
+	case []interface{}:
+		s.kinds["array"] = true
+		if s.elem == nil {
+			s.elem = newTypeShape()
+		}
+		for _, e := range t {
+			s.elem.add(e, order)
+		}
+	}
+}
+
+// nullable reports whether null was seen next to another kind.
+func (s *typeShape) nullable() bool {
+	return s.kinds["null"] && len(s.kinds) > 1
+}
+
+// only returns the single non-null kind of the shape, or "" when there are
+// none or several. An int and a float are both a float.
+func (s *typeShape) only() string {
+	var kinds []string
+	for k := range s.kinds {
+		if k != "null" {
+			kinds = append(kinds, k)
+		}
+	}
+	if len(kinds) == 2 && s.kinds["int"] && s.kinds["float"] {
+		return "float"
+	}
+	if len(kinds) != 1 {
+		return ""
+	}
+	return kinds[0]
+}
+
+func (s *typeShape) optional(k string) bool {
+	return s.seen[k] < s.objects
+}
+
+// enum returns the values of a string field that only ever held a few
+// distinct values, at least one of them more than once.
+func (s *typeShape) enum() []string {
+	if s.only() != "string" || len(s.values) > maxEnumValues || len(s.values) >= s.strings {
+		return nil
+	}
+	return s.values
+}
+
+func containsString(a []string, s string) bool {
+	for _, e := range a {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// typeNamer hands out unique type names in the order the types are declared.
+type typeNamer struct {
+	used    map[string]bool
+	objects []*typeShape
+}
+
+// name assigns names to s and every object shape below it. base is the
+// name for s; object elements of an array are named after the singular.
+func (n *typeNamer) name(s *typeShape, base string) {
+	switch s.only() {
+	case "object":
+		s.name = n.unique(base)
+		n.objects = append(n.objects, s)
+		for _, k := range s.keys {
+			n.name(s.fields[k], exportedName(k))
+		}
+	case "array":
+		item := singular(base)
+		if item == base {
+			item += "Item"
+		}
+		n.name(s.elem, item)
+	}
+}
+
+func (n *typeNamer) unique(base string) string {
+	name := base
+	for i := 2; n.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	n.used[name] = true
+	return name
+}
+
+// typeRootName names the root type after the last key of the query path,
+// in the singular when an element of it is selected.
+func typeRootName(qs string) string {
+	n, err := parseJidQuery(qs)
+	if err != nil || queryPath(qs) == "" {
+		return "Root"
+	}
+	indexed := false
+	for n.kind == qnSubexpression || n.kind == qnIndexExpression {
+		if r := n.children[1]; r.kind == qnIndex {
+			indexed = true
+			n = n.children[0]
+		} else {
+			n = r
+		}
+	}
+	if n.kind != qnField {
+		return "Root"
+	}
+	name := exportedName(n.value.(string))
+	if indexed {
+		name = singular(name)
+	}
+	return name
+}
+
+// goInitialisms are written in upper case in Go names, as golint expects.
+var goInitialisms = map[string]bool{
+	"api": true, "cpu": true, "css": true, "dns": true, "html": true, "http": true,
+	"https": true, "id": true, "ip": true, "json": true, "sql": true, "ssh": true,
+	"tcp": true, "tls": true, "ttl": true, "ui": true, "uid": true, "uri": true,
+	"url": true, "uuid": true, "xml": true,
+}
+
+// exportedName converts a JSON key such as "user_id" or "created-at" to an
+// exported Go / TypeScript type name ("UserID", "CreatedAt").
+func exportedName(key string) string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	var sb strings.Builder
+	for _, w := range words {
+		if goInitialisms[strings.ToLower(w)] {
+			sb.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		sb.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	name := sb.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "Field" + name
+	}
+	return name
+}
+
+// singular makes a best effort to turn a plural type name into a singular.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// inferTypes builds the shape of v and names its object types.
+func inferTypes(v interface{}, order keyOrder, root string) (*typeShape, *typeNamer) {
+	s := newTypeShape()
+	s.add(v, order)
+	n := &typeNamer{used: map[string]bool{}}
+	n.name(s, root)
+	return s, n
+}
+
+// RenderGoTypes returns Go type declarations for v. Objects become named
+// structs whose json tags keep the original keys; fields missing from some
+// elements of an array are pointers tagged omitempty.
+func RenderGoTypes(v interface{}, order keyOrder, root string) string {
+	s, n := inferTypes(v, order, root)
+	var sb strings.Builder
+	if s.only() != "object" {
+		fmt.Fprintf(&sb, "type %s %s\n\n", root, goType(s, false))
+	}
+	for _, o := range n.objects {
+		fmt.Fprintf(&sb, "type %s struct {\n", o.name)
+		used := map[string]bool{}
+		for _, k := range o.keys {
+			name := exportedName(k)
+			for i := 2; used[name]; i++ {
+				name = fmt.Sprintf("%s%d", exportedName(k), i)
+			}
+			used[name] = true
+			f := o.fields[k]
+			optional := o.optional(k)
+			tag := k
+			if optional {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&sb, "\t%s %s `json:%q`\n", name, goType(f, optional || f.nullable()), tag)
+		}
+		sb.WriteString("}\n\n")
+	}
+	src := []byte(sb.String())
+	if b, err := format.Source(src); err == nil {
+		src = b
+	}
+	return strings.TrimSpace(string(src))
+}
+
+// goType returns the Go type of s. pointer asks for a type that can be nil.
+func goType(s *typeShape, pointer bool) string {
+	var t string
+	switch s.only() {
+	case "bool":
+		t = "bool"
+	case "int":
+		t = "int64"
+	case "float":
+		t = "float64"
+	case "string":
+		t = "string"
+	case "object":
+		t = s.name
+	case "array":
+		return "[]" + goType(s.elem, s.elem.nullable())
+	default:
+		return "interface{}"
+	}
+	if pointer {
+		return "*" + t
+	}
+	return t
+}
+
+// RenderTSTypes returns TypeScript declarations for v. Objects become
+// exported interfaces; fields missing from some elements are optional.
+func RenderTSTypes(v interface{}, order keyOrder, root string) string {
+	s, n := inferTypes(v, order, root)
+	var sb strings.Builder
+	if s.only() != "object" {
+		fmt.Fprintf(&sb, "export type %s = %s;\n\n", root, tsType(s))
+	}
+	for _, o := range n.objects {
+		fmt.Fprintf(&sb, "export interface %s {\n", o.name)
+		for _, k := range o.keys {
+			name := k
+			if !reIdentifier.MatchString(k) {
+				name = jsonQuote(k)
+			}
+			if o.optional(k) {
+				name += "?"
+			}
+			fmt.Fprintf(&sb, "  %s: %s;\n", name, tsType(o.fields[k]))
+		}
+		sb.WriteString("}\n\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+var tsKinds = map[string]string{"bool": "boolean", "int": "number", "float": "number", "string": "string", "null": "null"}
+
+// tsType returns the TypeScript type of s; mixed kinds become a union.
+func tsType(s *typeShape) string {
+	var parts []string
+	seen := map[string]bool{}
+	for _, k := range []string{"string", "int", "float", "bool", "object", "array", "null"} {
+		if !s.kinds[k] {
+			continue
+		}
+		var t string
+		switch k {
+		case "object":
+			t = s.name
+			if t == "" {
+				t = "Record<string, unknown>"
+			}
+		case "array":
+			t = tsType(s.elem)
+			if strings.Contains(t, " ") {
+				t = "(" + t + ")"
+			}
+			t += "[]"
+		default:
+			t = tsKinds[k]
+		}
+		if !seen[t] {
+			seen[t] = true
+			parts = append(parts, t)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,94 @@
+package jid
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const typesTestJSON = `{"data": {"users": [
+  {"user_id": 1, "name": "Ann", "score": 1.5, "address": {"city": "Oslo", "zip-code": "0150"}, "tags": ["a"], "manager": null},
+  {"user_id": 2, "name": "Bob", "score": 2, "tags": [], "manager": {"user_id": 1}, "e.mail": "bob@example.com"}
+], "next": null, "items": []}}`
+
+func TestRenderGoTypes(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(typesTestJSON), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+
+	s, err := jm.Render(NewQueryWithString(".data"), &OutputOption{Format: OutputGo}, true)
+	assert.Nil(err)
+	assert.Equal("type Data struct {\n"+
+		"\tUsers []User        `json:\"users\"`\n"+
+		"\tNext  interface{}   `json:\"next\"`\n"+
+		"\tItems []interface{} `json:\"items\"`\n"+
+		"}\n\n"+
+		"type User struct {\n"+
+		"\tUserID  int64    `json:\"user_id\"`\n"+
+		"\tName    string   `json:\"name\"`\n"+
+		"\tScore   float64  `json:\"score\"`\n"+
+		"\tAddress *Address `json:\"address,omitempty\"`\n"+
+		"\tTags    []string `json:\"tags\"`\n"+
+		"\tManager *Manager `json:\"manager\"`\n"+
+		"\tEMail   *string  `json:\"e.mail,omitempty\"`\n"+
+		"}\n\n"+
+		"type Address struct {\n"+
+		"\tCity    string `json:\"city\"`\n"+
+		"\tZipCode string `json:\"zip-code\"`\n"+
+		"}\n\n"+
+		"type Manager struct {\n"+
+		"\tUserID int64 `json:\"user_id\"`\n"+
+		"}", s)
+
+	s, _ = jm.Render(NewQueryWithString(".data.users[0].tags"), &OutputOption{Format: OutputGo}, true)
+	assert.Equal("type Tags []string", s)
+}
+
+func TestRenderTSTypes(t *testing.T) {
+	var assert = assert.New(t)
+
+	jm, err := NewJsonManagerWithOption(bytes.NewBufferString(typesTestJSON), &InputOption{KeyOrder: true})
+	assert.Nil(err)
+
+	s, err := jm.Render(NewQueryWithString(".data.users"), &OutputOption{Format: OutputTS}, true)
+	assert.Nil(err)
+	assert.Equal(`export type Users = User[];
+
+export interface User {
+  user_id: number;
+  name: string;
+  score: number;
+  address?: Address;
+  tags: string[];
+  manager: Manager | null;
+  "e.mail"?: string;
+}
+
+export interface Address {
+  city: string;
+  "zip-code": string;
+}
+
+export interface Manager {
+  user_id: number;
+}`, s)
+
+	s, _ = jm.Render(NewQueryWithString(".data.users[1]"), &OutputOption{Format: OutputTS}, true)
+	assert.Regexp("^export interface User {", s)
+
+	jm, _ = NewJsonManagerWithOption(bytes.NewBufferString(`[1, "a", null, [true]]`), &InputOption{KeyOrder: true})
+	s, _ = jm.Render(NewQueryWithString("."), &OutputOption{Format: OutputTS}, true)
+	assert.Equal("export type Root = (string | number | boolean[] | null)[];", s)
+}
+
+func TestExportedName(t *testing.T) {
+	var assert = assert.New(t)
+
+	assert.Equal("UserID", exportedName("user_id"))
+	assert.Equal("CreatedAt", exportedName("createdAt"))
+	assert.Equal("AvatarURL", exportedName("avatar-url"))
+	assert.Equal("Field2fa", exportedName("2fa"))
+	assert.Equal("Field", exportedName("$"))
+}
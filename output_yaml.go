@@ -0,0 +1,85 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// yaml11Bools are plain scalars YAML 1.1 parsers (still common in Kubernetes
+// tooling) read as booleans; they are quoted so they stay strings.
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true, "off": true, "Off": true, "OFF": true,
+}
+
+// RenderYAML renders v, a value decoded from JSON, as a YAML document. Object
+// keys follow order and multi-line strings become literal block scalars.
+func RenderYAML(v interface{}, order keyOrder) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(v, order)); err != nil {
+		return "", errors.Wrap(err, "failure yaml encode")
+	}
+	if err := enc.Close(); err != nil {
+		return "", errors.Wrap(err, "failure yaml encode")
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func yamlNode(v interface{}, order keyOrder) *yaml.Node {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if len(t) == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		for _, k := range order.keys(t) {
+			n.Content = append(n.Content, yamlString(k), yamlNode(t[k], order))
+		}
+		return n
+	case []interface{}:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if len(t) == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		for _, e := range t {
+			n.Content = append(n.Content, yamlNode(e, order))
+		}
+		return n
+	case string:
+		n := yamlString(t)
+		if strings.Contains(strings.TrimRight(t, "\n"), "\n") {
+			n.Style = yaml.LiteralStyle
+		}
+		return n
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(t, 10)}
+	case float64:
+		// as JSON writes it, so 1 stays an int as it is in the input
+		return yamlNode(json.Number(compactJSON(t)), order)
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+func yamlString(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	if yaml11Bools[s] {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
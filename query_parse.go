@@ -0,0 +1,634 @@
+package jid
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// go-jmespath keeps its syntax tree private, so queries are parsed again here
+// when they are translated to another query language. The grammar and binding
+// powers follow the JMESPath specification.
+
+type queryNodeKind int
+
+const (
+	qnIdentity queryNodeKind = iota
+	qnCurrent
+	qnField
+	qnIndex
+	qnSlice
+	qnSubexpression
+	qnIndexExpression
+	qnProjection
+	qnValueProjection
+	qnFilterProjection
+	qnFlatten
+	qnPipe
+	qnOr
+	qnAnd
+	qnNot
+	qnComparator
+	qnLiteral
+	qnFunction
+	qnExpref
+	qnMultiSelectList
+	qnMultiSelectHash
+)
+
+// queryNode is a node of a parsed query. value holds the field name, index,
+// slice bounds, comparator, literal value, function name or hash keys.
+type queryNode struct {
+	kind     queryNodeKind
+	value    interface{}
+	children []*queryNode
+}
+
+// queryNodeNames describe the constructs in translation errors.
+var queryNodeNames = map[queryNodeKind]string{
+	qnSlice:            "a slice",
+	qnProjection:       "a [*] projection",
+	qnValueProjection:  "a .* projection",
+	qnFilterProjection: "a [?...] filter",
+	qnFlatten:          "a [] flatten",
+	qnPipe:             "a pipe",
+	qnOr:               "||",
+	qnAnd:              "&&",
+	qnNot:              "!",
+	qnComparator:       "a comparison",
+	qnLiteral:          "a literal",
+	qnExpref:           "an & expression",
+	qnMultiSelectList:  "a multi-select list",
+	qnMultiSelectHash:  "a multi-select hash",
+}
+
+func (n *queryNode) describe() string {
+	switch n.kind {
+	case qnFunction:
+		return n.value.(string) + "()"
+	case qnIndex:
+		if n.value.(int) < 0 {
+			return "a negative index"
+		}
+		return "an index"
+	case qnProjection:
+		// flatten and slices project what follows them
+		if left := n.children[0]; left.kind == qnFlatten {
+			return left.describe()
+		} else if left.kind == qnIndexExpression && left.children[1].kind == qnSlice {
+			return left.children[1].describe()
+		}
+	}
+	if s, ok := queryNodeNames[n.kind]; ok {
+		return s
+	}
+	return "this query"
+}
+
+type queryTokenKind int
+
+const (
+	qtEOF queryTokenKind = iota
+	qtIdentifier
+	qtQuotedIdentifier
+	qtNumber
+	qtRawString
+	qtLiteral
+	qtDot
+	qtStar
+	qtLbracket
+	qtRbracket
+	qtFlatten
+	qtFilter
+	qtLbrace
+	qtRbrace
+	qtLparen
+	qtRparen
+	qtComma
+	qtColon
+	qtPipe
+	qtOr
+	qtAnd
+	qtNot
+	qtCurrent
+	qtExpref
+	qtComparator
+)
+
+var queryBindingPowers = map[queryTokenKind]int{
+	qtPipe:       1,
+	qtOr:         2,
+	qtAnd:        3,
+	qtComparator: 5,
+	qtFlatten:    9,
+	qtStar:       20,
+	qtFilter:     21,
+	qtDot:        40,
+	qtNot:        45,
+	qtLbrace:     50,
+	qtLbracket:   55,
+	qtLparen:     60,
+}
+
+type queryToken struct {
+	kind  queryTokenKind
+	value string
+	pos   int
+}
+
+// lexQuery splits a JMESPath expression into tokens. A \"quoted\" key as
+// jid's completion writes it is read as a quoted identifier.
+func lexQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	simple := map[byte]queryTokenKind{
+		'.': qtDot, '*': qtStar, ']': qtRbracket, '{': qtLbrace, '}': qtRbrace,
+		'(': qtLparen, ')': qtRparen, ',': qtComma, ':': qtColon, '@': qtCurrent,
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case simple[c] != 0:
+			tokens = append(tokens, queryToken{kind: simple[c], value: string(c), pos: start})
+			i++
+			continue
+		case c == '[':
+			switch {
+			case strings.HasPrefix(s[i:], "[]"):
+				tokens = append(tokens, queryToken{kind: qtFlatten, value: "[]", pos: start})
+				i += 2
+			case strings.HasPrefix(s[i:], "[?"):
+				tokens = append(tokens, queryToken{kind: qtFilter, value: "[?", pos: start})
+				i += 2
+			default:
+				tokens = append(tokens, queryToken{kind: qtLbracket, value: "[", pos: start})
+				i++
+			}
+			continue
+		case c == '|' || c == '&':
+			double := map[byte]queryTokenKind{'|': qtOr, '&': qtAnd}[c]
+			single := map[byte]queryTokenKind{'|': qtPipe, '&': qtExpref}[c]
+			if i+1 < len(s) && s[i+1] == c {
+				tokens = append(tokens, queryToken{kind: double, value: s[i : i+2], pos: start})
+				i += 2
+			} else {
+				tokens = append(tokens, queryToken{kind: single, value: string(c), pos: start})
+				i++
+			}
+			continue
+		case c == '<' || c == '>' || c == '=' || c == '!':
+			if i+1 < len(s) && s[i+1] == '=' {
+				tokens = append(tokens, queryToken{kind: qtComparator, value: s[i : i+2], pos: start})
+				i += 2
+				continue
+			}
+			if c == '=' {
+				return nil, errors.Errorf("unexpected = at %d", start)
+			}
+			kind := qtComparator
+			if c == '!' {
+				kind = qtNot
+			}
+			tokens = append(tokens, queryToken{kind: kind, value: string(c), pos: start})
+			i++
+			continue
+		case c == '-' || (c >= '0' && c <= '9'):
+			i++
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			if s[start:i] == "-" {
+				return nil, errors.Errorf("unexpected - at %d", start)
+			}
+			tokens = append(tokens, queryToken{kind: qtNumber, value: s[start:i], pos: start})
+			continue
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			for i < len(s) && (s[i] == '_' || (s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= '0' && s[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: qtIdentifier, value: s[start:i], pos: start})
+			continue
+		case c == '\\' && i+1 < len(s) && s[i+1] == '"':
+			end := strings.Index(s[i+2:], `\"`)
+			if end < 0 {
+				return nil, errors.Errorf("unterminated quoted key at %d", start)
+			}
+			tokens = append(tokens, queryToken{kind: qtQuotedIdentifier, value: s[i+2 : i+2+end], pos: start})
+			i += end + 4
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, errors.Errorf("unterminated %c at %d", c, start)
+			}
+			body := s[i+1 : end]
+			i = end + 1
+			switch c {
+			case '"':
+				name, err := strconv.Unquote(`"` + body + `"`)
+				if err != nil {
+					return nil, errors.Errorf("invalid quoted key at %d", start)
+				}
+				tokens = append(tokens, queryToken{kind: qtQuotedIdentifier, value: name, pos: start})
+			case '\'':
+				tokens = append(tokens, queryToken{kind: qtRawString, value: strings.ReplaceAll(body, `\'`, `'`), pos: start})
+			default:
+				tokens = append(tokens, queryToken{kind: qtLiteral, value: strings.ReplaceAll(body, "\\`", "`"), pos: start})
+			}
+			continue
+		}
+		return nil, errors.Errorf("unexpected %c at %d", c, start)
+	}
+	return append(tokens, queryToken{kind: qtEOF, pos: len(s)}), nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	i      int
+}
+
+// parseQuery parses a JMESPath expression.
+func parseQuery(s string) (*queryNode, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	n, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(0); t.kind != qtEOF {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *queryParser) peek(n int) queryToken {
+	if p.i+n < len(p.tokens) {
+		return p.tokens[p.i+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.peek(0)
+	if p.i < len(p.tokens)-1 {
+		p.i++
+	}
+	return t
+}
+
+func (p *queryParser) unexpected(t queryToken) error {
+	if t.kind == qtEOF {
+		return errors.New("incomplete query")
+	}
+	return errors.Errorf("unexpected %s at %d", t.value, t.pos)
+}
+
+func (p *queryParser) match(kind queryTokenKind) error {
+	if t := p.next(); t.kind != kind {
+		return p.unexpected(t)
+	}
+	return nil
+}
+
+func (p *queryParser) expression(bp int) (*queryNode, error) {
+	left, err := p.nud(p.next())
+	if err != nil {
+		return nil, err
+	}
+	for bp < queryBindingPowers[p.peek(0).kind] {
+		if left, err = p.led(p.next(), left); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) nud(t queryToken) (*queryNode, error) {
+	identity := &queryNode{kind: qnIdentity}
+	switch t.kind {
+	case qtIdentifier, qtQuotedIdentifier:
+		return &queryNode{kind: qnField, value: t.value}, nil
+	case qtCurrent:
+		return &queryNode{kind: qnCurrent}, nil
+	case qtRawString:
+		return &queryNode{kind: qnLiteral, value: t.value}, nil
+	case qtLiteral:
+		var v interface{}
+		d := json.NewDecoder(strings.NewReader(t.value))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			// JMESPath reads an invalid JSON literal as a string.
+			v = strings.TrimSpace(t.value)
+		}
+		return &queryNode{kind: qnLiteral, value: v}, nil
+	case qtStar:
+		right, err := p.projectionRHS(queryBindingPowers[qtStar])
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: qnValueProjection, children: []*queryNode{identity, right}}, nil
+	case qtFlatten:
+		right, err := p.projectionRHS(queryBindingPowers[qtFlatten])
+		if err != nil {
+			return nil, err
+		}
+		flatten := &queryNode{kind: qnFlatten, children: []*queryNode{identity}}
+		return &queryNode{kind: qnProjection, children: []*queryNode{flatten, right}}, nil
+	case qtFilter:
+		return p.filter(identity)
+	case qtLbracket:
+		switch next := p.peek(0); {
+		case next.kind == qtNumber || next.kind == qtColon:
+			index, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			return p.projectIfSlice(identity, index)
+		case next.kind == qtStar && p.peek(1).kind == qtRbracket:
+			p.next()
+			p.next()
+			right, err := p.projectionRHS(queryBindingPowers[qtStar])
+			if err != nil {
+				return nil, err
+			}
+			return &queryNode{kind: qnProjection, children: []*queryNode{identity, right}}, nil
+		}
+		return p.multiSelectList()
+	case qtLbrace:
+		return p.multiSelectHash()
+	case qtNot:
+		n, err := p.expression(queryBindingPowers[qtNot])
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: qnNot, children: []*queryNode{n}}, nil
+	case qtExpref:
+		n, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: qnExpref, children: []*queryNode{n}}, nil
+	case qtLparen:
+		n, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		return n, p.match(qtRparen)
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *queryParser) led(t queryToken, left *queryNode) (*queryNode, error) {
+	switch t.kind {
+	case qtDot:
+		if p.peek(0).kind == qtStar {
+			p.next()
+			right, err := p.projectionRHS(queryBindingPowers[qtStar])
+			if err != nil {
+				return nil, err
+			}
+			return &queryNode{kind: qnValueProjection, children: []*queryNode{left, right}}, nil
+		}
+		right, err := p.dotRHS(queryBindingPowers[qtDot])
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: qnSubexpression, children: []*queryNode{left, right}}, nil
+	case qtPipe, qtOr, qtAnd:
+		right, err := p.expression(queryBindingPowers[t.kind])
+		if err != nil {
+			return nil, err
+		}
+		kind := map[queryTokenKind]queryNodeKind{qtPipe: qnPipe, qtOr: qnOr, qtAnd: qnAnd}[t.kind]
+		return &queryNode{kind: kind, children: []*queryNode{left, right}}, nil
+	case qtComparator:
+		right, err := p.expression(queryBindingPowers[qtComparator])
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: qnComparator, value: t.value, children: []*queryNode{left, right}}, nil
+	case qtLparen:
+		if left.kind != qnField {
+			return nil, p.unexpected(t)
+		}
+		fn := &queryNode{kind: qnFunction, value: left.value}
+		for p.peek(0).kind != qtRparen {
+			arg, err := p.expression(0)
+			if err != nil {
+				return nil, err
+			}
+			fn.children = append(fn.children, arg)
+			if p.peek(0).kind == qtComma {
+				p.next()
+			}
+		}
+		p.next()
+		return fn, nil
+	case qtFilter:
+		return p.filter(left)
+	case qtFlatten:
+		right, err := p.projectionRHS(queryBindingPowers[qtFlatten])
+		if err != nil {
+			return nil, err
+		}
+		flatten := &queryNode{kind: qnFlatten, children: []*queryNode{left}}
+		return &queryNode{kind: qnProjection, children: []*queryNode{flatten, right}}, nil
+	case qtLbracket:
+		if next := p.peek(0); next.kind == qtNumber || next.kind == qtColon {
+			index, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			return p.projectIfSlice(left, index)
+		}
+		if err := p.match(qtStar); err != nil {
+			return nil, err
+		}
+		if err := p.match(qtRbracket); err != nil {
+			return nil, err
+		}
+		right, err := p.projectionRHS(queryBindingPowers[qtStar])
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: qnProjection, children: []*queryNode{left, right}}, nil
+	}
+	return nil, p.unexpected(t)
+}
+
+// index parses the inside of [N] or [start:stop:step] after the [.
+func (p *queryParser) index() (*queryNode, error) {
+	if p.peek(0).kind != qtColon && p.peek(1).kind != qtColon {
+		t := p.next()
+		n, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, p.unexpected(t)
+		}
+		return &queryNode{kind: qnIndex, value: n}, p.match(qtRbracket)
+	}
+	var bounds [3]*int
+	for i := 0; i < 3; i++ {
+		t := p.next()
+		if t.kind == qtNumber {
+			n, _ := strconv.Atoi(t.value)
+			bounds[i] = &n
+			t = p.next()
+		}
+		if t.kind == qtRbracket {
+			return &queryNode{kind: qnSlice, value: bounds}, nil
+		}
+		if t.kind != qtColon || i == 2 {
+			return nil, p.unexpected(t)
+		}
+	}
+	return nil, p.unexpected(p.peek(0))
+}
+
+func (p *queryParser) projectIfSlice(left, index *queryNode) (*queryNode, error) {
+	n := &queryNode{kind: qnIndexExpression, children: []*queryNode{left, index}}
+	if index.kind != qnSlice {
+		return n, nil
+	}
+	right, err := p.projectionRHS(queryBindingPowers[qtStar])
+	if err != nil {
+		return nil, err
+	}
+	return &queryNode{kind: qnProjection, children: []*queryNode{n, right}}, nil
+}
+
+func (p *queryParser) filter(left *queryNode) (*queryNode, error) {
+	cond, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.match(qtRbracket); err != nil {
+		return nil, err
+	}
+	right := &queryNode{kind: qnIdentity}
+	if p.peek(0).kind != qtFlatten {
+		if right, err = p.projectionRHS(queryBindingPowers[qtFilter]); err != nil {
+			return nil, err
+		}
+	}
+	return &queryNode{kind: qnFilterProjection, children: []*queryNode{left, cond, right}}, nil
+}
+
+func (p *queryParser) dotRHS(bp int) (*queryNode, error) {
+	switch t := p.peek(0); t.kind {
+	case qtIdentifier, qtQuotedIdentifier, qtStar:
+		return p.expression(bp)
+	case qtLbracket:
+		p.next()
+		return p.multiSelectList()
+	case qtLbrace:
+		p.next()
+		return p.multiSelectHash()
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *queryParser) projectionRHS(bp int) (*queryNode, error) {
+	switch t := p.peek(0); {
+	case queryBindingPowers[t.kind] < 10:
+		return &queryNode{kind: qnIdentity}, nil
+	case t.kind == qtLbracket || t.kind == qtFilter:
+		return p.expression(bp)
+	case t.kind == qtDot:
+		p.next()
+		return p.dotRHS(bp)
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *queryParser) multiSelectList() (*queryNode, error) {
+	n := &queryNode{kind: qnMultiSelectList}
+	for {
+		e, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, e)
+		if t := p.next(); t.kind == qtRbracket {
+			return n, nil
+		} else if t.kind != qtComma {
+			return nil, p.unexpected(t)
+		}
+	}
+}
+
+func (p *queryParser) multiSelectHash() (*queryNode, error) {
+	n := &queryNode{kind: qnMultiSelectHash}
+	var keys []string
+	for {
+		k := p.next()
+		if k.kind != qtIdentifier && k.kind != qtQuotedIdentifier {
+			return nil, p.unexpected(k)
+		}
+		if err := p.match(qtColon); err != nil {
+			return nil, err
+		}
+		e, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k.value)
+		n.children = append(n.children, e)
+		if t := p.next(); t.kind == qtRbrace {
+			n.value = keys
+			return n, nil
+		} else if t.kind != qtComma {
+			return nil, p.unexpected(t)
+		}
+	}
+}
+
+// parseJidQuery parses a query as typed in jid: a dotted path (".a.b[0]")
+// or, when it uses any other syntax, a JMESPath expression with a leading dot.
+func parseJidQuery(qs string) (*queryNode, error) {
+	if isJMESPathQuery(qs) {
+		return parseQuery(jmespathExprFromQuery(qs))
+	}
+	n := &queryNode{kind: qnIdentity}
+	for _, keyword := range NewQueryWithString(qs).StringGetKeywords() {
+		if keyword == "" {
+			continue
+		}
+		var right *queryNode
+		kind := qnSubexpression
+		if strings.HasPrefix(keyword, "[") {
+			i, err := strconv.Atoi(strings.TrimSuffix(keyword[1:], "]"))
+			if err != nil || !strings.HasSuffix(keyword, "]") {
+				// flatten ([]) and slices ([1:3]) are JMESPath only
+				return parseQuery(jmespathExprFromQuery(qs))
+			}
+			right = &queryNode{kind: qnIndex, value: i}
+			kind = qnIndexExpression
+		} else {
+			right = &queryNode{kind: qnField, value: keyword}
+		}
+		if n.kind == qnIdentity {
+			if kind == qnSubexpression {
+				n = right
+				continue
+			}
+		}
+		n = &queryNode{kind: kind, children: []*queryNode{n, right}}
+	}
+	return n, nil
+}
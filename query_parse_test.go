@@ -0,0 +1,53 @@
+package jid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	var assert = assert.New(t)
+
+	n, err := parseQuery("a.b[0] | c")
+	assert.Nil(err)
+	assert.Equal(qnPipe, n.kind)
+	assert.Equal(qnSubexpression, n.children[0].kind)
+	assert.Equal(qnIndexExpression, n.children[0].children[1].kind)
+	assert.Equal(0, n.children[0].children[1].children[1].value)
+
+	n, err = parseQuery("a[?b == `1` && !c].d")
+	assert.Nil(err)
+	assert.Equal(qnFilterProjection, n.kind)
+	assert.Equal(qnAnd, n.children[1].kind)
+	assert.Equal(qnField, n.children[2].kind)
+
+	n, err = parseQuery("a[1:]")
+	assert.Nil(err)
+	assert.Equal(qnProjection, n.kind)
+	assert.Equal("a slice", n.describe())
+
+	_, err = parseQuery("a[?b ==")
+	assert.Equal("incomplete query", err.Error())
+	_, err = parseQuery("a ] b")
+	assert.Equal("unexpected ] at 2", err.Error())
+}
+
+func TestParseJidQuery(t *testing.T) {
+	var assert = assert.New(t)
+
+	n, err := parseJidQuery(`.\"a.b\"[2].c-d`)
+	assert.Nil(err)
+	assert.Equal(qnSubexpression, n.kind)
+	assert.Equal("c-d", n.children[1].value)
+	assert.Equal(qnIndexExpression, n.children[0].kind)
+	assert.Equal("a.b", n.children[0].children[0].value)
+
+	n, err = parseJidQuery(".")
+	assert.Nil(err)
+	assert.Equal(qnIdentity, n.kind)
+
+	n, err = parseJidQuery(".items[].id")
+	assert.Nil(err)
+	assert.Equal(qnProjection, n.kind)
+}
@@ -0,0 +1,621 @@
+package jid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// QuerySyntax is a query language the final query can be printed in.
+type QuerySyntax string
+
+const (
+	QuerySyntaxJq       QuerySyntax = "jq"
+	QuerySyntaxJSONPath QuerySyntax = "jsonpath"
+	QuerySyntaxJMESPath QuerySyntax = "jmespath"
+	QuerySyntaxPointer  QuerySyntax = "pointer"
+)
+
+var querySyntaxNames = map[QuerySyntax]string{
+	QuerySyntaxJq:       "jq",
+	QuerySyntaxJSONPath: "JSONPath",
+	QuerySyntaxJMESPath: "JMESPath",
+	QuerySyntaxPointer:  "JSON Pointer",
+}
+
+var reIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseQuerySyntax converts a --query-syntax value to a QuerySyntax.
+func ParseQuerySyntax(s string) (QuerySyntax, error) {
+	q := QuerySyntax(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := querySyntaxNames[q]; !ok {
+		return "", errors.Errorf("unknown query syntax: %s", s)
+	}
+	return q, nil
+}
+
+// TranslateQuery converts a query as typed in jid to the given query
+// language. Constructs the target has no equivalent for are reported as
+// errors rather than approximated. An empty query, as left when jid is
+// cancelled, gives an empty string.
+func TranslateQuery(qs string, syntax QuerySyntax) (string, error) {
+	name, ok := querySyntaxNames[syntax]
+	if !ok {
+		return "", errors.Errorf("unknown query syntax: %s", syntax)
+	}
+	if strings.TrimSpace(qs) == "" {
+		return "", nil
+	}
+	if reFromJSON.MatchString(qs) && syntax == QuerySyntaxJMESPath {
+		return "", errors.New("from_json(@) is a jid extension and has no JMESPath equivalent")
+	}
+	n, err := parseJidQuery(qs)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot parse query")
+	}
+	var s string
+	switch syntax {
+	case QuerySyntaxJq:
+		var e jqExpr
+		e, err = (&jqWriter{}).write(n)
+		s = e.s
+	case QuerySyntaxJSONPath:
+		s, err = jsonPathWrite(n, "$")
+	case QuerySyntaxJMESPath:
+		s, err = jmespathWrite(qs, n)
+	case QuerySyntaxPointer:
+		s, err = pointerWrite(n)
+	}
+	if err != nil {
+		return "", errors.Errorf("%s has no %s equivalent", err.Error(), name)
+	}
+	return s, nil
+}
+
+// untranslatable is returned by the writers for a node they cannot express.
+// TranslateQuery adds the name of the target language.
+type untranslatable struct{ n *queryNode }
+
+func (u untranslatable) Error() string { return u.n.describe() }
+
+// jmespathWrite returns the JMESPath expression jid evaluates for qs. Dotted
+// paths are written again with JMESPath quoting; anything else already is
+// JMESPath.
+func jmespathWrite(qs string, n *queryNode) (string, error) {
+	plain := true
+	var write func(n *queryNode) string
+	write = func(n *queryNode) string {
+		switch n.kind {
+		case qnIdentity:
+			return "@"
+		case qnField:
+			if reIdentifier.MatchString(n.value.(string)) {
+				return n.value.(string)
+			}
+			return jsonQuote(n.value.(string))
+		case qnIndex:
+			return fmt.Sprintf("[%d]", n.value.(int))
+		case qnSubexpression:
+			return write(n.children[0]) + "." + write(n.children[1])
+		case qnIndexExpression:
+			if n.children[0].kind == qnIdentity {
+				return write(n.children[1])
+			}
+			return write(n.children[0]) + write(n.children[1])
+		}
+		plain = false
+		return ""
+	}
+	if s := write(n); plain {
+		return s, nil
+	}
+	return jmespathExprFromQuery(qs), nil
+}
+
+// pointerWrite returns the RFC 6901 JSON Pointer of a plain path.
+func pointerWrite(n *queryNode) (string, error) {
+	switch n.kind {
+	case qnIdentity, qnCurrent:
+		return "", nil
+	case qnField:
+		s := strings.ReplaceAll(n.value.(string), "~", "~0")
+		return "/" + strings.ReplaceAll(s, "/", "~1"), nil
+	case qnIndex:
+		if n.value.(int) < 0 {
+			return "", untranslatable{n}
+		}
+		return "/" + strconv.Itoa(n.value.(int)), nil
+	case qnSubexpression, qnIndexExpression:
+		var sb strings.Builder
+		for _, c := range n.children {
+			s, err := pointerWrite(c)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), nil
+	}
+	return "", untranslatable{n}
+}
+
+// jsonPathWrite appends the segments of n to base, "$" for the document or
+// "@" for the element inside a filter.
+func jsonPathWrite(n *queryNode, base string) (string, error) {
+	switch n.kind {
+	case qnIdentity, qnCurrent:
+		return base, nil
+	case qnField:
+		name := n.value.(string)
+		if reIdentifier.MatchString(name) {
+			return base + "." + name, nil
+		}
+		return base + "['" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name) + "']", nil
+	case qnIndex:
+		return fmt.Sprintf("%s[%d]", base, n.value.(int)), nil
+	case qnSlice:
+		return base + "[" + sliceBounds(n) + "]", nil
+	case qnSubexpression, qnIndexExpression:
+		s, err := jsonPathWrite(n.children[0], base)
+		if err != nil {
+			return "", err
+		}
+		return jsonPathWrite(n.children[1], s)
+	case qnProjection, qnValueProjection:
+		s, err := jsonPathWrite(n.children[0], base)
+		if err != nil {
+			return "", err
+		}
+		left := n.children[0]
+		switch {
+		case n.kind == qnValueProjection:
+			s += ".*"
+		case left.kind == qnFlatten:
+			return "", untranslatable{left}
+		case left.kind != qnIndexExpression || left.children[1].kind != qnSlice:
+			s += "[*]"
+		}
+		return jsonPathWrite(n.children[1], s)
+	case qnFilterProjection:
+		s, err := jsonPathWrite(n.children[0], base)
+		if err != nil {
+			return "", err
+		}
+		cond, err := jsonPathFilter(n.children[1])
+		if err != nil {
+			return "", err
+		}
+		return jsonPathWrite(n.children[2], s+"[?("+cond+")]")
+	}
+	return "", untranslatable{n}
+}
+
+func jsonPathFilter(n *queryNode) (string, error) {
+	switch n.kind {
+	case qnAnd, qnOr:
+		op := map[queryNodeKind]string{qnAnd: " && ", qnOr: " || "}[n.kind]
+		l, err := jsonPathFilter(n.children[0])
+		if err != nil {
+			return "", err
+		}
+		r, err := jsonPathFilter(n.children[1])
+		if err != nil {
+			return "", err
+		}
+		return "(" + l + op + r + ")", nil
+	case qnNot:
+		s, err := jsonPathFilter(n.children[0])
+		if err != nil {
+			return "", err
+		}
+		return "!" + s, nil
+	case qnComparator:
+		l, err := jsonPathFilter(n.children[0])
+		if err != nil {
+			return "", err
+		}
+		r, err := jsonPathFilter(n.children[1])
+		if err != nil {
+			return "", err
+		}
+		return l + " " + n.value.(string) + " " + r, nil
+	case qnLiteral:
+		switch v := n.value.(type) {
+		case string:
+			return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'", nil
+		case map[string]interface{}, []interface{}:
+			return "", untranslatable{n}
+		}
+		return compactJSON(n.value), nil
+	}
+	return jsonPathWrite(n, "@")
+}
+
+func sliceBounds(n *queryNode) string {
+	bounds := n.value.([3]*int)
+	parts := make([]string, 0, 3)
+	for i, b := range bounds {
+		if i == 2 && b == nil {
+			break
+		}
+		if b == nil {
+			parts = append(parts, "")
+		} else {
+			parts = append(parts, strconv.Itoa(*b))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// jqExpr is a jq filter. A path (".a.b[0]", ".a[]") can be extended by
+// appending; anything else is joined with a pipe.
+type jqExpr struct {
+	s    string
+	path bool
+}
+
+var jqIdentity = jqExpr{s: ".", path: true}
+
+// pipe returns the filter that feeds the output of a into b.
+func (a jqExpr) pipe(b jqExpr) jqExpr {
+	switch {
+	case a.s == ".":
+		return b
+	case b.s == ".":
+		return a
+	case a.path && b.path && strings.HasPrefix(b.s, ".["):
+		return jqExpr{s: a.s + b.s[1:], path: true}
+	case a.path && b.path:
+		return jqExpr{s: a.s + b.s, path: true}
+	}
+	return jqExpr{s: a.s + " | " + b.s}
+}
+
+// operand returns e ready to be used inside a larger expression.
+func (e jqExpr) operand() string {
+	if e.path || !strings.ContainsAny(e.s, " ,") {
+		return e.s
+	}
+	return "(" + e.s + ")"
+}
+
+// jqWriter translates a query to jq. vars counts the variables bound for
+// function arguments that are not constants.
+type jqWriter struct {
+	vars int
+}
+
+// jqFunctions maps the one-argument JMESPath functions to jq filters applied
+// to the argument.
+var jqFunctions = map[string]string{
+	"abs":       "fabs",
+	"avg":       "add / length",
+	"ceil":      "ceil",
+	"floor":     "floor",
+	"from_json": "fromjson",
+	"keys":      "keys_unsorted",
+	"length":    "length",
+	"max":       "max",
+	"min":       "min",
+	"reverse":   "reverse",
+	"sort":      "sort",
+	"sum":       "add",
+	"to_array":  `if type == "array" then . else [.] end`,
+	"to_number": "tonumber",
+	"to_string": "tostring",
+	"type":      "type",
+	"values":    "[.[]]",
+}
+
+// jqByFunctions take an array and an &expression.
+var jqByFunctions = map[string]string{
+	"max_by":  "max_by",
+	"min_by":  "min_by",
+	"sort_by": "sort_by",
+}
+
+func (w *jqWriter) write(n *queryNode) (jqExpr, error) {
+	switch n.kind {
+	case qnIdentity, qnCurrent:
+		return jqIdentity, nil
+	case qnField:
+		name := n.value.(string)
+		if reIdentifier.MatchString(name) {
+			return jqExpr{s: "." + name, path: true}, nil
+		}
+		return jqExpr{s: "." + jsonQuote(name), path: true}, nil
+	case qnIndex:
+		return jqExpr{s: fmt.Sprintf(".[%d]", n.value.(int)), path: true}, nil
+	case qnSlice:
+		bounds := n.value.([3]*int)
+		if bounds[2] != nil && *bounds[2] != 1 {
+			return jqExpr{}, errors.New("a slice step")
+		}
+		b := bounds
+		b[2] = nil
+		return jqExpr{s: ".[" + sliceBounds(&queryNode{value: b}) + "]", path: true}, nil
+	case qnSubexpression, qnIndexExpression, qnPipe:
+		l, err := w.write(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		r, err := w.write(n.children[1])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		return l.pipe(r), nil
+	case qnFlatten:
+		l, err := w.write(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		return l.pipe(jqExpr{s: "flatten(1)"}), nil
+	case qnProjection, qnValueProjection, qnFilterProjection:
+		l, err := w.write(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		each := l.pipe(jqExpr{s: ".[]", path: true})
+		right := n.children[len(n.children)-1]
+		if n.kind == qnFilterProjection {
+			cond, err := w.condition(n.children[1])
+			if err != nil {
+				return jqExpr{}, err
+			}
+			each = each.pipe(jqExpr{s: "select(" + cond.s + ")"})
+		}
+		r, err := w.write(right)
+		if err != nil {
+			return jqExpr{}, err
+		}
+		// a projection leaves out the elements the right side makes null
+		return jqExpr{s: "[" + each.pipe(r).pipe(jqExpr{s: "values"}).s + "]"}, nil
+	case qnComparator:
+		l, err := w.write(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		r, err := w.write(n.children[1])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		return jqExpr{s: l.operand() + " " + n.value.(string) + " " + r.operand()}, nil
+	case qnAnd, qnOr:
+		// && and || give one of their operands, not a boolean
+		cond, err := w.condition(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		l, err := w.write(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		r, err := w.write(n.children[1])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		if n.kind == qnOr {
+			l, r = r, l
+		}
+		return jqExpr{s: "if " + cond.s + " then " + r.s + " else " + l.s + " end"}, nil
+	case qnNot:
+		return w.condition(n)
+	case qnLiteral:
+		return jqExpr{s: compactJSON(n.value)}, nil
+	case qnMultiSelectList:
+		items := make([]string, len(n.children))
+		for i, c := range n.children {
+			e, err := w.write(c)
+			if err != nil {
+				return jqExpr{}, err
+			}
+			items[i] = e.operand()
+		}
+		return jqExpr{s: "[" + strings.Join(items, ", ") + "]"}, nil
+	case qnMultiSelectHash:
+		keys := n.value.([]string)
+		items := make([]string, len(n.children))
+		for i, c := range n.children {
+			e, err := w.write(c)
+			if err != nil {
+				return jqExpr{}, err
+			}
+			k := keys[i]
+			if !reIdentifier.MatchString(k) {
+				k = jsonQuote(k)
+			}
+			items[i] = k + ": " + e.operand()
+		}
+		return jqExpr{s: "{" + strings.Join(items, ", ") + "}"}, nil
+	case qnFunction:
+		return w.function(n)
+	}
+	return jqExpr{}, untranslatable{n}
+}
+
+// jqFalsy tests a value the way JMESPath does: besides false and null, an
+// empty string, array or object is false.
+const jqFalsy = `IN(false, null, "", [], {})`
+
+// jqBooleans are the functions that always give true or false.
+var jqBooleans = map[string]bool{"contains": true, "ends_with": true, "starts_with": true}
+
+// condition translates n to a jq filter that gives true where n is truthy in
+// JMESPath, for filters and the operands of &&, || and !.
+func (w *jqWriter) condition(n *queryNode) (jqExpr, error) {
+	switch n.kind {
+	case qnAnd, qnOr:
+		l, err := w.condition(n.children[0])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		r, err := w.condition(n.children[1])
+		if err != nil {
+			return jqExpr{}, err
+		}
+		op := map[queryNodeKind]string{qnAnd: "and", qnOr: "or"}[n.kind]
+		return jqExpr{s: l.operand() + " " + op + " " + r.operand()}, nil
+	case qnNot:
+		c := n.children[0]
+		if !jqBoolean(c) {
+			e, err := w.write(c)
+			if err != nil {
+				return jqExpr{}, err
+			}
+			return e.pipe(jqExpr{s: jqFalsy}), nil
+		}
+		e, err := w.condition(c)
+		if err != nil {
+			return jqExpr{}, err
+		}
+		return e.pipe(jqExpr{s: "not"}), nil
+	}
+	e, err := w.write(n)
+	if err != nil || jqBoolean(n) {
+		return e, err
+	}
+	return e.pipe(jqExpr{s: jqFalsy + " | not"}), nil
+}
+
+// jqBoolean reports whether the translation of n gives true or false only.
+func jqBoolean(n *queryNode) bool {
+	switch n.kind {
+	case qnAnd, qnOr, qnNot, qnComparator:
+		return true
+	case qnFunction:
+		return jqBooleans[n.value.(string)]
+	}
+	return false
+}
+
+// function translates a JMESPath function call. Arguments other than the
+// subject are evaluated against the current value, so unless they are
+// constants they are bound to variables before the subject is piped.
+func (w *jqWriter) function(n *queryNode) (jqExpr, error) {
+	name := n.value.(string)
+	args := n.children
+	arg := func(i int) (jqExpr, error) {
+		if i >= len(args) {
+			return jqExpr{}, errors.Errorf("%s() with %d arguments", name, len(args))
+		}
+		if args[i].kind == qnExpref {
+			return w.write(args[i].children[0])
+		}
+		return w.write(args[i])
+	}
+	var binds []string
+	// param returns a constant argument as is and binds any other one.
+	param := func(i int) (string, error) {
+		e, err := arg(i)
+		if err != nil {
+			return "", err
+		}
+		if args[i].kind == qnLiteral {
+			return e.s, nil
+		}
+		w.vars++
+		v := fmt.Sprintf("$a%d", w.vars)
+		binds = append(binds, e.operand()+" as "+v+" | ")
+		return v, nil
+	}
+	bound := func(e jqExpr) jqExpr {
+		if len(binds) == 0 {
+			return e
+		}
+		return jqExpr{s: strings.Join(binds, "") + e.s}
+	}
+
+	if f, ok := jqFunctions[name]; ok && len(args) == 1 {
+		subject, err := arg(0)
+		if err != nil {
+			return jqExpr{}, err
+		}
+		return subject.pipe(jqExpr{s: f}), nil
+	}
+	var subject jqExpr
+	var call string
+	var err error
+	switch name {
+	case "sort_by", "max_by", "min_by", "map":
+		si, ei := 0, 1
+		if name == "map" {
+			si, ei = 1, 0
+		}
+		if len(args) != 2 || args[ei].kind != qnExpref {
+			return jqExpr{}, errors.Errorf("%s() with these arguments", name)
+		}
+		e, err := arg(ei)
+		if err != nil {
+			return jqExpr{}, err
+		}
+		if subject, err = arg(si); err != nil {
+			return jqExpr{}, err
+		}
+		f := name
+		if jf, ok := jqByFunctions[name]; ok {
+			f = jf
+		}
+		return subject.pipe(jqExpr{s: f + "(" + e.s + ")"}), nil
+	case "starts_with", "ends_with", "contains":
+		var p string
+		if p, err = param(1); err != nil {
+			return jqExpr{}, err
+		}
+		subject, err = arg(0)
+		call = map[string]string{
+			"starts_with": "startswith(" + p + ")",
+			"ends_with":   "endswith(" + p + ")",
+			"contains":    "index(" + p + ") != null",
+		}[name]
+	case "join":
+		var sep string
+		if sep, err = param(0); err != nil {
+			return jqExpr{}, err
+		}
+		subject, err = arg(1)
+		call = "join(" + sep + ")"
+	case "merge", "not_null":
+		if len(args) == 0 {
+			return jqExpr{}, errors.Errorf("%s() without arguments", name)
+		}
+		items := make([]string, len(args))
+		for i := range args {
+			e, err := arg(i)
+			if err != nil {
+				return jqExpr{}, err
+			}
+			items[i] = e.operand()
+		}
+		if name == "not_null" {
+			// jq's // would also skip false
+			return jqExpr{s: "[" + strings.Join(items, ", ") + " | values][0]"}, nil
+		}
+		return jqExpr{s: strings.Join(items, " + ")}, nil
+	default:
+		return jqExpr{}, untranslatable{n}
+	}
+	if err != nil {
+		return jqExpr{}, err
+	}
+	return bound(subject.pipe(jqExpr{s: call})), nil
+}
+
+func jsonQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func compactJSON(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,135 @@
+package jid
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuerySyntax(t *testing.T) {
+	var assert = assert.New(t)
+
+	s, err := ParseQuerySyntax("JQ")
+	assert.Nil(err)
+	assert.Equal(QuerySyntaxJq, s)
+
+	_, err = ParseQuerySyntax("xpath")
+	assert.Regexp("unknown query syntax: xpath", err.Error())
+}
+
+func TestTranslateQuery(t *testing.T) {
+	var assert = assert.New(t)
+
+	tests := []struct {
+		query                           string
+		jq, jsonpath, jmespath, pointer string
+	}{
+		{".", ".", "$", "@", ""},
+		{".users[0].name", ".users[0].name", "$.users[0].name", "users[0].name", "/users/0/name"},
+		{`.\"a.b\".c/d`, `."a.b"."c/d"`, "$['a.b']['c/d']", `"a.b"."c/d"`, "/a.b/c~1d"},
+		{".users[*].name", "[.users[].name | values]", "$.users[*].name", "users[*].name", ""},
+		{".users[?age > `30`].name", "[.users[] | select(.age > 30) | .name | values]", "$.users[?(@.age > 30)].name", "users[?age > `30`].name", ""},
+		{".items[-1]", ".items[-1]", "$.items[-1]", "items[-1]", ""},
+		{".a[1:3]", "[.a[1:3][] | values]", "$.a[1:3]", "a[1:3]", ""},
+		{".users | sort_by(@, &age)[0]", ".users | sort_by(.age) | .[0]", "", "users | sort_by(@, &age)[0]", ""},
+		{".body | from_json(@).id", ".body | fromjson | .id", "", "", ""},
+	}
+	for _, tt := range tests {
+		for syntax, want := range map[QuerySyntax]string{
+			QuerySyntaxJq: tt.jq, QuerySyntaxJSONPath: tt.jsonpath,
+			QuerySyntaxJMESPath: tt.jmespath, QuerySyntaxPointer: tt.pointer,
+		} {
+			got, err := TranslateQuery(tt.query, syntax)
+			if want == "" && !(syntax == QuerySyntaxPointer && tt.query == ".") {
+				assert.NotNil(err, "%s as %s", tt.query, syntax)
+				continue
+			}
+			assert.Nil(err, "%s as %s", tt.query, syntax)
+			assert.Equal(want, got, "%s as %s", tt.query, syntax)
+		}
+	}
+}
+
+func TestTranslateQueryErrors(t *testing.T) {
+	var assert = assert.New(t)
+
+	_, err := TranslateQuery(".users[*].name", QuerySyntaxPointer)
+	assert.Equal("a [*] projection has no JSON Pointer equivalent", err.Error())
+
+	_, err = TranslateQuery(".users | length(@)", QuerySyntaxJSONPath)
+	assert.Equal("a pipe has no JSONPath equivalent", err.Error())
+
+	_, err = TranslateQuery(".a[::2]", QuerySyntaxJq)
+	assert.Equal("a slice step has no jq equivalent", err.Error())
+
+	_, err = TranslateQuery(".x | to_array(@)", QuerySyntaxJq)
+	assert.Nil(err)
+
+	_, err = TranslateQuery(".b | from_json(@)", QuerySyntaxJMESPath)
+	assert.Regexp("jid extension", err.Error())
+
+	// a cancelled session leaves no query
+	s, err := TranslateQuery("", QuerySyntaxJSONPath)
+	assert.Nil(err)
+	assert.Equal("", s)
+
+	_, err = TranslateQuery(".users[?age >", QuerySyntaxJq)
+	assert.Regexp("cannot parse query: incomplete query", err.Error())
+}
+
+func TestTranslateQueryToJqFunctions(t *testing.T) {
+	var assert = assert.New(t)
+
+	for q, want := range map[string]string{
+		". | keys(@)":                        "keys_unsorted",
+		".users | map(&name, @)":             ".users | map(.name)",
+		".tags | join(', ', @)":              `.tags | join(", ")`,
+		".users[?starts_with(name, 'A')]":    `[.users[] | select(.name | startswith("A")) | values]`,
+		".users[?contains(tags, role)]":      "[.users[] | select(.role as $a1 | .tags | index($a1) != null) | values]",
+		".users[?!active].{n: name, id: id}": `[.users[] | select(.active | IN(false, null, "", [], {})) | {n: .name, id: .id} | values]`,
+	} {
+		got, err := TranslateQuery(q, QuerySyntaxJq)
+		assert.Nil(err, q)
+		assert.Equal(want, got, q)
+	}
+}
+
+// TestTranslateQueryToJqResults runs the translations with jq, when it is
+// installed, and compares the results with jid's.
+func TestTranslateQueryToJqResults(t *testing.T) {
+	var assert = assert.New(t)
+
+	jq, err := exec.LookPath("jq")
+	if err != nil {
+		t.Skip("jq is not installed")
+	}
+	doc := `{"users":[{"name":"a","age":40,"active":"","tags":[]},{"age":20,"active":true,"tags":["x"]},null],"a":[null,1,2,{"b":[]}]}`
+	jm, err := NewJsonManager(bytes.NewBufferString(doc))
+	assert.Nil(err)
+	for _, q := range []string{
+		".users[*].name",
+		".users[*]",
+		".users[?active].age",
+		".users[?tags].age",
+		".users[?!active].age",
+		".users[?active && age > `30`].age",
+		".users[?age > `30`].name",
+		".users[?!(active && tags)].age",
+		".users[0] | not_null(active, name)",
+		".users[1] | not_null(name, `false`, age)",
+		".a[3].b[*]",
+	} {
+		want, _, _, err := jm.Get(NewQueryWithString(q), true)
+		assert.Nil(err, q)
+		expr, err := TranslateQuery(q, QuerySyntaxJq)
+		assert.Nil(err, q)
+		cmd := exec.Command(jq, "-cS", expr)
+		cmd.Stdin = strings.NewReader(doc)
+		got, err := cmd.Output()
+		assert.Nil(err, "%s: %s", q, expr)
+		assert.Equal(want, strings.TrimSpace(string(got)), "%s: %s", q, expr)
+	}
+}
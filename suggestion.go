@@ -20,7 +20,7 @@ type SuggestionInterface interface {
 // used both for completion and for the candidate list.
 var jmespathFunctions = []string{
 	"abs", "avg", "ceil", "contains", "ends_with", "floor",
-	"join", "keys", "length", "map", "max", "max_by",
+	"from_json", "join", "keys", "length", "map", "max", "max_by",
 	"merge", "min", "min_by", "not_null", "reverse",
 	"sort", "sort_by", "starts_with", "sum",
 	"to_array", "to_number", "to_string", "type", "values",
@@ -34,6 +34,7 @@ var jmespathFunctionDescriptions = map[string]string{
 	"contains":   "contains(@, value) — true if subject contains value",
 	"ends_with":  "ends_with(@, suffix) — true if string ends with suffix",
 	"floor":      "floor(@) — floor of a number",
+	"from_json":  "from_json(@) — decode a JSON string (jid extension, after a pipe)",
 	"join":       "join(glue, @) — join array of strings with glue",
 	"keys":       "keys(@) — array of keys of an object",
 	"length":     "length(@) — length of string, array, or object",
@@ -79,6 +80,7 @@ var jmespathFuncTemplates = map[string]struct {
 	"contains":    {"@, ''", 2, 0},        // cursor inside ''
 	"ends_with":   {"@, ''", 2, 0},        // cursor inside ''
 	"floor":       {"@", 0, 0},
+	"from_json":   {"@", 0, 0},
 	"join":        {"'', @", 5, 0},        // cursor inside '' (string separator)
 	"keys":        {"@", 0, 0},
 	"length":      {"@", 0, 0},
@@ -240,7 +242,7 @@ func getCurrentKeys(json *simplejson.Json) []string {
 // that accept that type as @. Types not in the map (UNKNOWN) show all functions.
 var jmespathFunctionsByType = map[SuggestionDataType][]string{
 	ARRAY: {
-		"avg", "contains", "join", "length", "map",
+		"avg", "contains", "from_json", "join", "length", "map",
 		"max", "max_by", "min", "min_by", "not_null",
 		"reverse", "sort", "sort_by", "sum",
 		"to_array", "to_string", "type",
@@ -250,7 +252,7 @@ var jmespathFunctionsByType = map[SuggestionDataType][]string{
 		"to_array", "to_string", "type", "values",
 	},
 	STRING: {
-		"contains", "ends_with", "length", "not_null",
+		"contains", "ends_with", "from_json", "length", "not_null",
 		"reverse", "starts_with",
 		"to_array", "to_number", "to_string", "type",
 	},
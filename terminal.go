@@ -32,6 +32,7 @@ type TerminalDrawAttributes struct {
 	PlaceholderLen    int
 	SelectedCandidate       string // field name to highlight in JSON; "" if none
 	SelectedCandidateIndent int    // indentation level of the target key
+	Status                  string // shown right-aligned on the filter line; "" if none
 }
 
 func NewTerminal(prompt string, defaultY int, monochrome bool) *Terminal {
@@ -63,6 +64,9 @@ func (t *Terminal) Draw(attr *TerminalDrawAttributes) error {
 	_, h := termbox.Size()
 
 	t.drawFilterLine(query, complete, attr.PlaceholderStart, attr.PlaceholderLen)
+	if attr.Status != "" {
+		t.drawStatus(attr.Status, runewidth.StringWidth(t.prompt+query+complete))
+	}
 
 	if len(candidates) > 0 {
 		y = t.drawCandidates(0, t.defaultY, candidateidx, candidates)
@@ -142,6 +146,20 @@ func (t *Terminal) drawFilterLine(qs string, complete string, phStart int, phLen
 	return nil
 }
 
+// drawStatus writes status at the right edge of the filter line. It is skipped
+// when the terminal is too narrow to keep it clear of the query (minX columns).
+func (t *Terminal) drawStatus(status string, minX int) {
+	w, _ := termbox.Size()
+	x := w - runewidth.StringWidth(status)
+	if x <= minX {
+		return
+	}
+	for _, ch := range status {
+		termbox.SetCell(x, 0, ch, termbox.ColorCyan, termbox.ColorDefault)
+		x += runewidth.RuneWidth(ch)
+	}
+}
+
 type termboxSprintfFuncer struct {
 	fg         termbox.Attribute
 	bg         termbox.Attribute
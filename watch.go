@@ -0,0 +1,108 @@
+package jid
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often watched files are checked for changes.
+var watchInterval = 500 * time.Millisecond
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{size: -1}
+	}
+	return fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+}
+
+// watcher polls a set of files and remembers which of them changed since the
+// changes were last taken.
+type watcher struct {
+	paths   []string
+	stamps  []fileStamp
+	mu      sync.Mutex
+	changed map[int]bool
+	done    chan struct{}
+}
+
+func newWatcher(paths []string) *watcher {
+	w := &watcher{
+		paths:   paths,
+		stamps:  make([]fileStamp, len(paths)),
+		changed: map[int]bool{},
+	}
+	for i, path := range paths {
+		w.stamps[i] = statFile(path)
+	}
+	return w
+}
+
+// start polls the files every watchInterval and calls notify after a change
+// was seen, until stop is called.
+func (w *watcher) start(notify func()) {
+	w.done = make(chan struct{})
+	go func(done chan struct{}) {
+		t := time.NewTicker(watchInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if w.poll() {
+					notify()
+				}
+			}
+		}
+	}(w.done)
+}
+
+func (w *watcher) stop() {
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
+}
+
+// poll checks every file once and reports whether any of them changed.
+// A file that cannot be read (e.g. while it is being replaced) is not
+// reported until it is back.
+func (w *watcher) poll() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	found := false
+	for i, path := range w.paths {
+		s := statFile(path)
+		if s == w.stamps[i] {
+			continue
+		}
+		w.stamps[i] = s
+		if s.size >= 0 {
+			w.changed[i] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// takeChanged returns the indexes of the files that changed, in order, and
+// forgets them.
+func (w *watcher) takeChanged() []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var idx []int
+	for i := range w.changed {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	w.changed = map[int]bool{}
+	return idx
+}
@@ -0,0 +1,69 @@
+package jid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	var assert = assert.New(t)
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	_ = os.WriteFile(a, []byte(`{}`), 0644)
+	_ = os.WriteFile(b, []byte(`{}`), 0644)
+
+	w := newWatcher([]string{a, b})
+	assert.False(w.poll())
+	assert.Nil(w.takeChanged())
+
+	_ = os.WriteFile(b, []byte(`{"status":"done"}`), 0644)
+	assert.True(w.poll())
+	assert.False(w.poll())
+	assert.Equal([]int{1}, w.takeChanged())
+	assert.Nil(w.takeChanged())
+
+	// same size, newer modification time
+	_ = os.WriteFile(a, []byte(`[]`), 0644)
+	_ = os.Chtimes(a, time.Now(), time.Now().Add(time.Second))
+	assert.True(w.poll())
+	assert.Equal([]int{0}, w.takeChanged())
+
+	// a removed file is reported once it is back
+	_ = os.Remove(a)
+	assert.False(w.poll())
+	_ = os.WriteFile(a, []byte(`{"v":2}`), 0644)
+	assert.True(w.poll())
+	assert.Equal([]int{0}, w.takeChanged())
+}
+
+func TestWatcherStart(t *testing.T) {
+	defer func(d time.Duration) { watchInterval = d }(watchInterval)
+	watchInterval = 10 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "status.json")
+	_ = os.WriteFile(path, []byte(`{}`), 0644)
+
+	w := newWatcher([]string{path})
+	notified := make(chan struct{}, 1)
+	w.start(func() {
+		select {
+		case notified <- struct{}{}:
+		default:
+		}
+	})
+	defer w.stop()
+
+	_ = os.WriteFile(path, []byte(`{"progress":1}`), 0644)
+	select {
+	case <-notified:
+		assert.Equal(t, []int{0}, w.takeChanged())
+	case <-time.After(2 * time.Second):
+		t.Fatal("change was not noticed")
+	}
+}